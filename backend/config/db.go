@@ -4,7 +4,9 @@ import (
 	"context"
 	"log"
 	"os"
+	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -12,6 +14,19 @@ import (
 var DB *mongo.Client
 var UsersCollection *mongo.Collection
 var TasksCollection *mongo.Collection // Add this
+var RefreshTokensCollection *mongo.Collection
+var RevokedTokensCollection *mongo.Collection
+var PasswordResetsCollection *mongo.Collection
+var LoginAttemptsCollection *mongo.Collection
+var PersonalAccessTokensCollection *mongo.Collection
+var WorkspacesCollection *mongo.Collection
+var WorkspaceMembersCollection *mongo.Collection
+var WorkspaceInvitationsCollection *mongo.Collection
+var SessionsCollection *mongo.Collection
+var AuditEventsCollection *mongo.Collection
+var MagicLinksCollection *mongo.Collection
+var ServiceAccountsCollection *mongo.Collection
+var ServiceAccountKeysCollection *mongo.Collection
 
 func ConnectDB() {
 	uri := os.Getenv("MONGO_URI")
@@ -27,6 +42,142 @@ func ConnectDB() {
 	DB = client
 	UsersCollection = client.Database("taskapp").Collection("users")
 	TasksCollection = client.Database("taskapp").Collection("tasks") // Add this
+	RefreshTokensCollection = client.Database("taskapp").Collection("refresh_tokens")
+	RevokedTokensCollection = client.Database("taskapp").Collection("revoked_tokens")
+	PasswordResetsCollection = client.Database("taskapp").Collection("password_resets")
+	LoginAttemptsCollection = client.Database("taskapp").Collection("login_attempts")
+	PersonalAccessTokensCollection = client.Database("taskapp").Collection("personal_access_tokens")
+	WorkspacesCollection = client.Database("taskapp").Collection("workspaces")
+	WorkspaceMembersCollection = client.Database("taskapp").Collection("workspace_members")
+	WorkspaceInvitationsCollection = client.Database("taskapp").Collection("workspace_invitations")
+	SessionsCollection = client.Database("taskapp").Collection("sessions")
+	AuditEventsCollection = client.Database("taskapp").Collection("audit_events")
+	MagicLinksCollection = client.Database("taskapp").Collection("magic_links")
+	ServiceAccountsCollection = client.Database("taskapp").Collection("service_accounts")
+	ServiceAccountKeysCollection = client.Database("taskapp").Collection("service_account_keys")
+
+	ensureIndexes()
 
 	log.Println("Connected to MongoDB")
 }
+
+// ensureIndexes creates the indexes the auth collections rely on
+func ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := UsersCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "oidc_issuer", Value: 1}, {Key: "oidc_subject", Value: 1}}, Options: options.Index().SetSparse(true)},
+	})
+	if err != nil {
+		log.Fatal("Failed to create user indexes:", err)
+	}
+
+	_, err = TasksCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "created_by", Value: 1}}},
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "assigned_to", Value: 1}}},
+	})
+	if err != nil {
+		log.Fatal("Failed to create task indexes:", err)
+	}
+
+	_, err = RefreshTokensCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "family_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Fatal("Failed to create refresh token indexes:", err)
+	}
+
+	_, err = RevokedTokensCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "jti", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "issued_before", Value: 1}}},
+		{Keys: bson.D{{Key: "session_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Fatal("Failed to create revoked token indexes:", err)
+	}
+
+	_, err = PasswordResetsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Fatal("Failed to create password reset indexes:", err)
+	}
+
+	_, err = MagicLinksCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Fatal("Failed to create magic link indexes:", err)
+	}
+
+	_, err = LoginAttemptsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Fatal("Failed to create login attempt indexes:", err)
+	}
+
+	_, err = PersonalAccessTokensCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	if err != nil {
+		log.Fatal("Failed to create personal access token indexes:", err)
+	}
+
+	_, err = ServiceAccountsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "workspace_id", Value: 1}}})
+	if err != nil {
+		log.Fatal("Failed to create service account indexes:", err)
+	}
+
+	_, err = ServiceAccountKeysCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "service_account_id", Value: 1}}},
+	})
+	if err != nil {
+		log.Fatal("Failed to create service account key indexes:", err)
+	}
+
+	_, err = WorkspaceMembersCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	if err != nil {
+		log.Fatal("Failed to create workspace member indexes:", err)
+	}
+
+	_, err = WorkspaceInvitationsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "email", Value: 1}}},
+	})
+	if err != nil {
+		log.Fatal("Failed to create workspace invitation indexes:", err)
+	}
+
+	_, err = SessionsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "last_seen_at", Value: -1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Fatal("Failed to create session indexes:", err)
+	}
+
+	_, err = AuditEventsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "target_user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "event", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		log.Fatal("Failed to create audit event indexes:", err)
+	}
+}
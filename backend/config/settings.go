@@ -0,0 +1,79 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetEnv returns the environment variable or fallback when it is unset
+func GetEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// GetEnvBool parses a boolean environment variable, defaulting to fallback
+func GetEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// GetEnvInt parses an integer environment variable, defaulting to fallback
+func GetEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// GetEnvDuration parses a duration such as "15m" or "24h", defaulting to fallback
+func GetEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// AppBaseURL is the public URL used when building links sent by email
+func AppBaseURL() string {
+	return GetEnv("APP_BASE_URL", "http://localhost:5000")
+}
+
+// FrontendURL is where links that need a UI page (such as password reset) point
+func FrontendURL() string {
+	return GetEnv("FRONTEND_URL", AppBaseURL())
+}
+
+// ProxyConfig returns the Fiber settings that make c.IP() the client's address
+// when the app runs behind reverse proxies. List them, as IPs or CIDR ranges,
+// in the comma separated TRUSTED_PROXIES; the client IP is then read from
+// PROXY_IP_HEADER (X-Forwarded-For by default). Per-IP login lockouts and
+// audit events depend on this: without it every client behind the proxy shares
+// the proxy's address. The proxy must overwrite the header, not append to one
+// sent by the client, because the first address in it is used. Requests from
+// anywhere else never have the header believed.
+func ProxyConfig() fiber.Config {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+
+	return fiber.Config{
+		ProxyHeader:             GetEnv("PROXY_IP_HEADER", fiber.HeaderXForwardedFor),
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          proxies,
+		EnableIPValidation:      true,
+	}
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// clientIP returns what c.IP() reports for a request forwarded for 203.0.113.7
+func clientIP(t *testing.T) string {
+	t.Helper()
+
+	app := fiber.New(ProxyConfig())
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString(c.IP()) })
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderXForwardedFor, "203.0.113.7")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestProxyConfigTrustsListedProxies(t *testing.T) {
+	// app.Test connections come from 0.0.0.0
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 0.0.0.0")
+	if ip := clientIP(t); ip != "203.0.113.7" {
+		t.Fatalf("c.IP() = %q, want the forwarded client address", ip)
+	}
+}
+
+func TestProxyConfigIgnoresHeaderFromUntrustedPeers(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "")
+	if ip := clientIP(t); ip == "203.0.113.7" {
+		t.Fatal("believed X-Forwarded-For from an untrusted peer")
+	}
+}
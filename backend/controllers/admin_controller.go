@@ -0,0 +1,321 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"backend/config"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+)
+
+var errInvalidUserID = errors.New("invalid user ID")
+
+const (
+	defaultAdminPageSize = 50
+	maxAdminPageSize     = 200
+)
+
+// ListUsers - Lists users for admins, with search and filters:
+// q (name or email substring), role, disabled (true/false), page, per_page
+func ListUsers(c *fiber.Ctx) error {
+	filter := services.UserFilter{Query: c.Query("q")}
+
+	if role := models.Role(c.Query("role")); role != "" {
+		if !role.IsValid() {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid role"})
+		}
+		filter.Role = role
+	}
+	if value := c.Query("disabled"); value != "" {
+		disabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "disabled must be true or false"})
+		}
+		filter.Disabled = &disabled
+	}
+
+	page := c.QueryInt("page", 1)
+	perPage := c.QueryInt("per_page", defaultAdminPageSize)
+	if page < 1 || perPage < 1 || perPage > maxAdminPageSize {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "page must be at least 1 and per_page between 1 and " + strconv.Itoa(maxAdminPageSize)})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	users, total, err := services.ListUsers(ctx, filter, page, perPage)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch users"})
+	}
+
+	result := make([]fiber.Map, 0, len(users))
+	for i := range users {
+		result = append(result, adminUserView(&users[i]))
+	}
+
+	return c.JSON(fiber.Map{
+		"users":    result,
+		"page":     page,
+		"per_page": perPage,
+		"total":    total,
+	})
+}
+
+// GetUser - Returns one user's account details for admins
+func GetUser(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := adminTargetUser(ctx, c)
+	if err != nil {
+		return userLookupFailed(c, err)
+	}
+
+	return c.JSON(adminUserView(user))
+}
+
+// DisableUser - Blocks sign-in for a user and revokes their sessions
+func DisableUser(c *fiber.Ctx) error {
+	if c.Params("id") == c.Locals("userID").(string) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "You can't disable your own account"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := adminTargetUser(ctx, c)
+	if err != nil {
+		return userLookupFailed(c, err)
+	}
+
+	if err := services.SetUserDisabled(ctx, user.ID, true); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to disable user"})
+	}
+
+	recordAudit(c, models.AuditEvent{Event: models.AuditUserDisable, Outcome: models.AuditSuccess, TargetUserID: &user.ID})
+	return c.JSON(fiber.Map{"message": "User disabled successfully"})
+}
+
+// EnableUser - Lets a disabled user sign in again
+func EnableUser(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := adminTargetUser(ctx, c)
+	if err != nil {
+		return userLookupFailed(c, err)
+	}
+
+	if err := services.SetUserDisabled(ctx, user.ID, false); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to enable user"})
+	}
+
+	recordAudit(c, models.AuditEvent{Event: models.AuditUserEnable, Outcome: models.AuditSuccess, TargetUserID: &user.ID})
+	return c.JSON(fiber.Map{"message": "User enabled successfully"})
+}
+
+// ForceLogoutUser - Signs a user out of every session
+func ForceLogoutUser(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := adminTargetUser(ctx, c)
+	if err != nil {
+		return userLookupFailed(c, err)
+	}
+
+	if err := services.RevokeAllUserTokens(ctx, user.ID); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke sessions"})
+	}
+
+	recordAudit(c, models.AuditEvent{Event: models.AuditUserLogout, Outcome: models.AuditSuccess, TargetUserID: &user.ID})
+	return c.JSON(fiber.Map{"message": "User logged out of all sessions"})
+}
+
+// ResetUserTwoFactor - Turns off 2FA for a user who lost their authenticator
+func ResetUserTwoFactor(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := adminTargetUser(ctx, c)
+	if err != nil {
+		return userLookupFailed(c, err)
+	}
+	if !user.TwoFactorEnabled {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is not enabled for this user"})
+	}
+
+	if err := services.ResetTwoFactor(ctx, user.ID); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset two-factor authentication"})
+	}
+
+	recordAudit(c, models.AuditEvent{Event: models.AuditUserTwoFactorReset, Outcome: models.AuditSuccess, TargetUserID: &user.ID})
+	return c.JSON(fiber.Map{"message": "Two-factor authentication reset successfully"})
+}
+
+// UpdateUserRole - Changes a user's global role
+func UpdateUserRole(c *fiber.Ctx) error {
+	var request struct {
+		Role models.Role `json:"role" validate:"required"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+	if !request.Role.IsValid() {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid role"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := adminTargetUser(ctx, c)
+	if err != nil {
+		return userLookupFailed(c, err)
+	}
+
+	err = services.SetUserRole(ctx, user.ID, request.Role)
+	if err == services.ErrLastAdmin {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	} else if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update role"})
+	}
+
+	recordAudit(c, models.AuditEvent{
+		Event:        models.AuditRoleChange,
+		Outcome:      models.AuditSuccess,
+		TargetUserID: &user.ID,
+		Metadata:     map[string]string{"from": string(user.Role), "to": string(request.Role)},
+	})
+	return c.JSON(fiber.Map{"message": "Role updated successfully"})
+}
+
+// ImpersonateUser - Issues a short-lived access token that acts as another
+// user, for support. The token names the admin in its "act" claim, can't be
+// refreshed and can't reach account management routes.
+func ImpersonateUser(c *fiber.Ctx) error {
+	var request struct {
+		Reason string `json:"reason" validate:"required,max=500"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	adminID := c.Locals("userID").(string)
+	if c.Params("id") == adminID {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "You can't impersonate yourself"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := adminTargetUser(ctx, c)
+	if err != nil {
+		return userLookupFailed(c, err)
+	}
+	// Impersonating another admin would be a way around their audit trail
+	if user.Role == models.RoleAdmin {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Admins can't be impersonated"})
+	}
+	if user.Disabled || user.DeletionScheduledFor != nil {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "User account is not active"})
+	}
+
+	token, claims, err := utils.GenerateImpersonationJWT(user.ID.Hex(), adminID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
+	}
+
+	recordAudit(c, models.AuditEvent{
+		Event:        models.AuditUserImpersonate,
+		Outcome:      models.AuditSuccess,
+		TargetUserID: &user.ID,
+		Metadata:     map[string]string{"reason": request.Reason, "jti": claims.ID},
+	})
+	return c.JSON(fiber.Map{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(utils.AccessTokenTTL.Seconds()),
+	})
+}
+
+// UnlockUser - Lifts a login lockout on a user's account
+func UnlockUser(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := adminTargetUser(ctx, c)
+	if err != nil {
+		return userLookupFailed(c, err)
+	}
+
+	if err := services.UnlockAccount(ctx, user.Email); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to unlock user"})
+	}
+
+	recordAudit(c, models.AuditEvent{Event: models.AuditUserUnlock, Outcome: models.AuditSuccess, TargetUserID: &user.ID})
+	return c.JSON(fiber.Map{"message": "User unlocked successfully"})
+}
+
+// adminTargetUser loads the user named by the :id route parameter
+func adminTargetUser(ctx context.Context, c *fiber.Ctx) (*models.User, error) {
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, errInvalidUserID
+	}
+
+	var user models.User
+	if err := config.UsersCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// userLookupFailed responds to an adminTargetUser error
+func userLookupFailed(c *fiber.Ctx, err error) error {
+	switch err {
+	case errInvalidUserID:
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	case mongo.ErrNoDocuments:
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	default:
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch user"})
+	}
+}
+
+// adminUserView is what admins see of an account; secrets are never included
+func adminUserView(user *models.User) fiber.Map {
+	role := user.Role
+	if role == "" {
+		role = models.RoleMember
+	}
+	return fiber.Map{
+		"id":                     user.ID.Hex(),
+		"name":                   user.Name,
+		"email":                  user.Email,
+		"role":                   role,
+		"email_verified":         user.EmailVerified,
+		"two_factor_enabled":     user.TwoFactorEnabled,
+		"sso_linked":             user.OIDCIssuer != "",
+		"disabled":               user.Disabled,
+		"disabled_at":            user.DisabledAt,
+		"deletion_scheduled_for": user.DeletionScheduledFor,
+		"created_at":             user.CreatedAt,
+		"updated_at":             user.UpdatedAt,
+	}
+}
@@ -2,18 +2,22 @@ package controllers
 
 import (
 	"backend/services"
+	"backend/utils"
 	"github.com/gofiber/fiber/v2"
 )
 
 // GenerateTaskSuggestions - Get AI-generated tasks for a project
 func GenerateTaskSuggestions(c *fiber.Ctx) error {
 	var request struct {
-		ProjectDescription string `json:"project_description"`
+		ProjectDescription string `json:"project_description" validate:"required,max=5000"`
 	}
 
 	if err := c.BodyParser(&request); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
 	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
 
 	suggestions, err := services.GetTaskSuggestions(request.ProjectDescription)
 	if err != nil {
@@ -26,12 +30,15 @@ func GenerateTaskSuggestions(c *fiber.Ctx) error {
 // ImproveTask - Enhance task descriptions using AI
 func ImproveTask(c *fiber.Ctx) error {
 	var request struct {
-		TaskDescription string `json:"task_description"`
+		TaskDescription string `json:"task_description" validate:"required,max=5000"`
 	}
 
 	if err := c.BodyParser(&request); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
 	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
 
 	improvedTask, err := services.ImproveTaskDescription(request.TaskDescription)
 	if err != nil {
@@ -44,12 +51,15 @@ func ImproveTask(c *fiber.Ctx) error {
 // AssignTaskPriority - AI assigns priority levels to tasks
 func AssignTaskPriority(c *fiber.Ctx) error {
 	var request struct {
-		TaskDescription string `json:"task_description"`
+		TaskDescription string `json:"task_description" validate:"required,max=5000"`
 	}
 
 	if err := c.BodyParser(&request); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
 	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
 
 	priority, err := services.AssignTaskPriority(request.TaskDescription)
 	if err != nil {
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+)
+
+// recordAudit appends an audit event for the current request, filling in the
+// client IP and user agent. Unless the event names its actor, the actor is the
+// authenticated user, plus the impersonating admin if there is one. A failure
+// to record is logged but never fails the request.
+func recordAudit(c *fiber.Ctx, entry models.AuditEvent) {
+	entry.IP = c.IP()
+	entry.UserAgent = c.Get(fiber.HeaderUserAgent)
+
+	if entry.ActorID == nil {
+		if userID, ok := c.Locals("userID").(string); ok {
+			if id, err := primitive.ObjectIDFromHex(userID); err == nil {
+				entry.ActorID = &id
+			}
+		}
+		if actorType(c) == models.ActorServiceAccount {
+			entry.ActorType = models.ActorServiceAccount
+		}
+	}
+	if claims, ok := c.Locals("claims").(*utils.Claims); ok && claims.Actor != nil {
+		if id, err := primitive.ObjectIDFromHex(claims.Actor.Subject); err == nil {
+			entry.ImpersonatorID = &id
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := services.RecordAuditEvent(ctx, &entry); err != nil {
+		log.Printf("Failed to record audit event %s: %v", entry.Event, err)
+	}
+}
+
+// ListAuditEvents - Queries the audit log for admins. Filters: user_id (actor
+// or target), event, from and to (RFC 3339), page, per_page.
+func ListAuditEvents(c *fiber.Ctx) error {
+	filter := services.AuditFilter{Event: c.Query("event")}
+
+	if value := c.Query("user_id"); value != "" {
+		id, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+		}
+		filter.UserID = id
+	}
+	for param, dst := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": param + " must be an RFC 3339 timestamp"})
+			}
+			*dst = t
+		}
+	}
+
+	page := c.QueryInt("page", 1)
+	perPage := c.QueryInt("per_page", defaultAdminPageSize)
+	if page < 1 || perPage < 1 || perPage > maxAdminPageSize {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "page must be at least 1 and per_page between 1 and " + strconv.Itoa(maxAdminPageSize)})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	events, total, err := services.ListAuditEvents(ctx, filter, page, perPage)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch audit events"})
+	}
+
+	return c.JSON(fiber.Map{
+		"events":   events,
+		"page":     page,
+		"per_page": perPage,
+		"total":    total,
+	})
+}
@@ -3,7 +3,10 @@ package controllers
 import (
 	"context"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -13,19 +16,35 @@ import (
 
 	"backend/config"
 	"backend/models"
+	"backend/services"
 	"backend/utils"
 )
 
 // Register a new user
 func Register(c *fiber.Ctx) error {
-	user := new(models.User)
-	if err := c.BodyParser(user); err != nil {
+	// Only these fields come from the client; role, verification, 2FA and
+	// account state are always set here
+	var request struct {
+		Name     string `json:"name" validate:"required,min=3,max=50"`
+		Email    string `json:"email" validate:"required,email"`
+		Password string `json:"password" validate:"required"`
+		// Registrations started from an invite link carry its token
+		InviteToken string `json:"invite_token"`
+	}
+	if err := c.BodyParser(&request); err != nil {
 		log.Println("Body parsing error:", err)
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
-	// Debug: Print parsed user data before processing
-	log.Printf("Parsed user data: %+v\n", user)
+	request.Email = utils.NormalizeEmail(request.Email)
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+	if errs := utils.ValidatePassword("password", request.Password, request.Email, request.Name); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	user := &models.User{Name: request.Name, Email: request.Email}
 
 	// Check if email is already registered
 	var existingUser models.User
@@ -34,88 +53,548 @@ func Register(c *fiber.Ctx) error {
 
 	err := config.UsersCollection.FindOne(ctx, bson.M{"email": user.Email}).Decode(&existingUser)
 	if err == nil {
+		recordAudit(c, models.AuditEvent{
+			Event:        models.AuditRegister,
+			Outcome:      models.AuditFailure,
+			TargetUserID: &existingUser.ID,
+			Metadata:     map[string]string{"email": user.Email, "reason": "email_taken"},
+		})
 		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Email already exists"})
 	} else if err != mongo.ErrNoDocuments {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
 	}
 
+	if request.InviteToken != "" {
+		switch _, err := services.CheckInvitation(ctx, request.InviteToken, user.Email); err {
+		case nil:
+		case services.ErrInvitationInvalid:
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid, expired or revoked invitation"})
+		case services.ErrInvitationEmailMismatch:
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "This invitation was sent to a different email address"})
+		default:
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+		}
+	}
+
 	// Hash password
-	hashedPassword, err := utils.HashPassword(user.Password)
+	hashedPassword, err := utils.HashPassword(request.Password)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to hash password"})
 	}
 
-	log.Println("Hashed Password:", hashedPassword)
-	log.Println("Original Password:", user.Password)
-
+	now := time.Now()
 	user.Password = hashedPassword
 	user.ID = primitive.NewObjectID()
-	user.CreatedAt = time.Now()
-	user.UpdatedAt = time.Now()
+	user.Role = models.RoleMember
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	user.VerificationSentAt = &now
+
+	// Following the emailed invite link already proves ownership of the address
+	if request.InviteToken != "" {
+		user.EmailVerified = true
+		user.EmailVerifiedAt = &now
+		user.VerificationSentAt = nil
+	}
 
 	_, err = config.UsersCollection.InsertOne(ctx, user)
-	if err != nil {
+	if mongo.IsDuplicateKeyError(err) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Email already exists"})
+	} else if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create user"})
+	}
+
+	// Every user starts with a personal workspace they administer; without it
+	// the account couldn't use tasks, so registration fails as a whole
+	if _, err := services.EnsurePersonalWorkspace(ctx, user); err != nil {
+		log.Println("Failed to create personal workspace:", err)
+		if _, err := config.UsersCollection.DeleteOne(ctx, bson.M{"_id": user.ID}); err != nil {
+			log.Println("Failed to roll back registration:", err)
+		}
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create user"})
 	}
+	recordAudit(c, models.AuditEvent{Event: models.AuditRegister, Outcome: models.AuditSuccess, ActorID: &user.ID, TargetUserID: &user.ID})
+
+	if request.InviteToken != "" {
+		invitation, err := services.AcceptInvitation(ctx, request.InviteToken, user)
+		if err != nil {
+			log.Println("Failed to accept invitation:", err)
+			return c.Status(http.StatusCreated).JSON(fiber.Map{"message": "User registered successfully, but the invitation could not be accepted"})
+		}
+		return c.Status(http.StatusCreated).JSON(fiber.Map{"message": "User registered successfully", "workspace_id": invitation.WorkspaceID})
+	}
 
-	return c.Status(http.StatusCreated).JSON(fiber.Map{"message": "User registered successfully"})
+	// The account exists either way; a failed email can be re-sent later
+	if err := services.SendVerificationEmail(user); err != nil {
+		log.Println("Failed to send verification email:", err)
+	}
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{"message": "User registered successfully, please verify your email"})
 }
 
 
-// Login user and return JWT token
 // Login user and return JWT token in cookie and response
 func Login(c *fiber.Ctx) error {
 	var loginData struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email    string `json:"email" validate:"required"`
+		Password string `json:"password" validate:"required"`
 	}
 
 	if err := c.BodyParser(&loginData); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
 	}
+	if errs := utils.ValidateStruct(loginData); errs != nil {
+		return validationFailed(c, errs)
+	}
+	loginData.Email = utils.NormalizeEmail(loginData.Email)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Refuse to check passwords while the account or client IP is locked out
+	wait, err := services.LoginRetryAfter(ctx, loginData.Email, c.IP())
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+	if wait > 0 {
+		auditLoginFailure(c, loginData.Email, nil, "locked_out")
+		return tooManyAttempts(c, wait)
+	}
 
 	// Find user by email
 	var user models.User
+	err = config.UsersCollection.FindOne(ctx, bson.M{"email": loginData.Email}).Decode(&user)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+
+	// Verify password. Unknown emails are checked against a dummy hash so they
+	// take as long as a wrong password, and count as failures too so they
+	// can't be probed freely.
+	passwordOK := utils.CheckPasswordHash(loginData.Password, user.Password)
+	if err == mongo.ErrNoDocuments || !passwordOK {
+		var target *models.User
+		if err == nil {
+			target = &user
+		}
+		auditLoginFailure(c, loginData.Email, target, "invalid_credentials")
+
+		lockout, err := services.RecordLoginFailure(ctx, loginData.Email, c.IP())
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+		}
+		if lockout > 0 {
+			auditLockout(c, loginData.Email, target, lockout)
+			return tooManyAttempts(c, lockout)
+		}
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid email or password"})
+	}
+
+	if err := services.ResetLoginFailures(ctx, loginData.Email); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+
+	// Upgrade bcrypt or outdated Argon2id hashes now that we have the plaintext
+	if utils.NeedsRehash(user.Password) {
+		rehashPassword(ctx, &user, loginData.Password)
+	}
+
+	// Optionally block sign-in until the address has been confirmed
+	if !user.EmailVerified && config.GetEnvBool("REQUIRE_EMAIL_VERIFICATION", false) {
+		auditLoginFailure(c, user.Email, &user, "email_not_verified")
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Email address not verified", "code": "email_not_verified"})
+	}
+
+	return continueLogin(ctx, c, &user, "password")
+}
+
+// continueLogin finishes a login once the first factor (a password, a magic
+// link or single sign-on) has been checked: it refuses unusable accounts and
+// asks for the second factor when 2FA is on. Browser flows with a post-login
+// redirect get the challenge in the URL fragment instead of a JSON body.
+func continueLogin(ctx context.Context, c *fiber.Ctx, user *models.User, method string) error {
+	if user.Disabled {
+		auditLoginFailure(c, user.Email, user, "account_disabled")
+		return accountDisabled(c)
+	}
+	if user.DeletionScheduledFor != nil {
+		auditLoginFailure(c, user.Email, user, "account_pending_deletion")
+		return accountPendingDeletion(c)
+	}
+
+	// With 2FA on, the first factor only earns a short-lived challenge token
+	if user.TwoFactorEnabled {
+		challenge, err := utils.GenerateActionToken(utils.PurposeTwoFactorChallenge, user.ID.Hex(), "", services.TwoFactorChallengeTTL)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
+		}
+		if redirect := postLoginRedirect(method); redirect != "" {
+			fragment := url.Values{"two_factor_required": {"true"}, "challenge_token": {challenge}}
+			return c.Redirect(redirect+"#"+fragment.Encode(), http.StatusFound)
+		}
+		return c.JSON(fiber.Map{
+			"message":             "Two-factor authentication required",
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+			"expires_in":          int(services.TwoFactorChallengeTTL.Seconds()),
+		})
+	}
+
+	if redirect := postLoginRedirect(method); redirect != "" {
+		if _, err := issueSession(ctx, c, user, method); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
+		}
+		return c.Redirect(redirect, http.StatusFound)
+	}
+	return completeLogin(ctx, c, user, method)
+}
+
+// postLoginRedirect returns where a browser login lands on the frontend, or
+// "" when the caller expects the usual JSON response
+func postLoginRedirect(method string) string {
+	if method == "oidc" {
+		return config.GetEnv("OIDC_POST_LOGIN_REDIRECT", "")
+	}
+	return ""
+}
+
+// completeLogin issues the session for an authenticated user and sends the login response
+func completeLogin(ctx context.Context, c *fiber.Ctx, user *models.User, method string) error {
+	// Issue access and refresh tokens as HTTP-only cookies
+	accessToken, err := issueSession(ctx, c, user, method)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
+	}
+
+	// Send response with user details (excluding password). The access token is
+	// also returned so non-browser clients can send it as a Bearer token.
+	return c.JSON(fiber.Map{
+		"message":      "Login successful",
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(utils.AccessTokenTTL.Seconds()),
+		"user":         userProfile(user),
+	})
+}
+
+// Refresh exchanges a refresh token for a new access token and a rotated refresh token
+func Refresh(c *fiber.Ctx) error {
+	// Browsers send the cookie; CLI and mobile clients may post the token instead
+	raw := c.Cookies("refresh_token")
+	fromBody := false
+	if raw == "" {
+		var body struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		if err := c.BodyParser(&body); err == nil && body.RefreshToken != "" {
+			raw = body.RefreshToken
+			fromBody = true
+		}
+	}
+	if raw == "" {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Missing refresh token"})
+	}
+	// The cookie is sent on cross-site requests too, so require the CSRF header
+	if !fromBody && !utils.ValidCSRFToken(c.Cookies(utils.CSRFCookieName), c.Get(utils.CSRFHeaderName)) {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Missing or invalid CSRF token", "code": "csrf_token_invalid"})
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	err := config.UsersCollection.FindOne(ctx, bson.M{"email": loginData.Email}).Decode(&user)
-	if err == mongo.ErrNoDocuments {
-		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid email or password"})
+	newRaw, refreshToken, err := services.RotateRefreshToken(ctx, raw)
+	if err == services.ErrRefreshTokenReused {
+		// A rotated-out token coming back means it was stolen; the family is now revoked
+		recordAudit(c, models.AuditEvent{Event: models.AuditTokenRevoke, Outcome: models.AuditFailure, Metadata: map[string]string{"reason": "refresh_token_reused"}})
+		clearAuthCookies(c)
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token reuse detected, please log in again"})
+	} else if err == services.ErrRefreshTokenInvalid {
+		clearAuthCookies(c)
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired refresh token"})
 	} else if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to refresh token"})
 	}
 
-	// Verify password
-	if !utils.CheckPasswordHash(loginData.Password, user.Password) {
-		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid email or password"})
+	accessToken, claims, err := utils.GenerateJWT(refreshToken.UserID.Hex(), refreshToken.FamilyID.Hex())
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(user.ID.Hex())
+	if err := services.RenewSession(ctx, refreshToken.FamilyID, claims.ID, c.IP()); err != nil {
+		log.Println("Failed to renew session:", err)
+	}
+
+	setAuthCookies(c, accessToken, newRaw)
+	// Keep the CSRF token the frontend already holds, extending its lifetime
+	if _, err := setCSRFCookie(c, c.Cookies(utils.CSRFCookieName)); err != nil {
+		log.Println("Failed to set CSRF cookie:", err)
+	}
+
+	response := fiber.Map{"message": "Token refreshed"}
+	if fromBody {
+		response["access_token"] = accessToken
+		response["refresh_token"] = newRaw
+		response["expires_in"] = int(utils.AccessTokenTTL.Seconds())
+	}
+	return c.JSON(response)
+}
+
+// CSRFToken - Returns the CSRF token for the X-CSRF-Token header, setting the
+// cookie if there isn't one yet. Frontends on another origin can't read the
+// cookie directly and use this instead.
+func CSRFToken(c *fiber.Ctx) error {
+	token, err := setCSRFCookie(c, c.Cookies(utils.CSRFCookieName))
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
 	}
 
-	// Set token in HTTP-only cookie
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(fiber.Map{"csrf_token": token, "header_name": utils.CSRFHeaderName})
+}
+
+// Logout revokes the current session: its access tokens, including ones
+// issued earlier by refresh, and its refresh token family
+func Logout(c *fiber.Ctx) error {
+	claims := c.Locals("claims").(*utils.Claims)
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := services.RevokeAccessToken(ctx, claims); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to log out"})
+	}
+
+	if familyID, err := primitive.ObjectIDFromHex(claims.SessionID); err == nil {
+		if err := services.RevokeTokenFamily(ctx, userID, familyID); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to log out"})
+		}
+	}
+
+	clearAuthCookies(c)
+	recordAudit(c, models.AuditEvent{Event: models.AuditLogout, Outcome: models.AuditSuccess, Metadata: map[string]string{"session_id": claims.SessionID}})
+	return c.JSON(fiber.Map{"message": "Logged out successfully"})
+}
+
+// LogoutAll revokes every access and refresh token issued to the user
+func LogoutAll(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := services.RevokeAllUserTokens(ctx, userID); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to log out"})
+	}
+
+	// Also denylist the caller's own token explicitly in case it was issued this second
+	if err := services.RevokeAccessToken(ctx, c.Locals("claims").(*utils.Claims)); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to log out"})
+	}
+
+	clearAuthCookies(c)
+	recordAudit(c, models.AuditEvent{Event: models.AuditLogout, Outcome: models.AuditSuccess, TargetUserID: &userID, Metadata: map[string]string{"scope": "all"}})
+	return c.JSON(fiber.Map{"message": "Logged out of all sessions"})
+}
+
+// rehashPassword replaces the user's stored hash with one using the current
+// algorithm and parameters. Failures are logged; the old hash keeps working.
+func rehashPassword(ctx context.Context, user *models.User, password string) {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		log.Println("Failed to rehash password:", err)
+		return
+	}
+
+	// Matching the old hash avoids overwriting a password changed meanwhile
+	_, err = config.UsersCollection.UpdateOne(ctx,
+		bson.M{"_id": user.ID, "password": user.Password},
+		bson.M{"$set": bson.M{"password": hashedPassword}},
+	)
+	if err != nil {
+		log.Println("Failed to store rehashed password:", err)
+		return
+	}
+	user.Password = hashedPassword
+}
+
+// auditLoginFailure records a rejected login. user is nil when no account
+// matches the email.
+func auditLoginFailure(c *fiber.Ctx, email string, user *models.User, reason string) {
+	entry := models.AuditEvent{
+		Event:    models.AuditLogin,
+		Outcome:  models.AuditFailure,
+		Metadata: map[string]string{"email": email, "reason": reason},
+	}
+	if user != nil {
+		entry.TargetUserID = &user.ID
+	}
+	recordAudit(c, entry)
+}
+
+// auditLockout records that failed logins locked the account or client IP
+func auditLockout(c *fiber.Ctx, email string, user *models.User, lockout time.Duration) {
+	entry := models.AuditEvent{
+		Event:    models.AuditLoginLockout,
+		Outcome:  models.AuditFailure,
+		Metadata: map[string]string{"email": email, "locked_for": lockout.Round(time.Second).String()},
+	}
+	if user != nil {
+		entry.TargetUserID = &user.ID
+	}
+	recordAudit(c, entry)
+}
+
+// confirmPassword re-checks a signed-in user's password before a sensitive
+// change. Wrong guesses count against the same lockout as Login, so a stolen
+// session can't be used to guess the password freely. When it returns false
+// the response has been sent and the caller returns the error. A failure is
+// audited as event unless event is empty.
+func confirmPassword(ctx context.Context, c *fiber.Ctx, user *models.User, password, event, message string) (bool, error) {
+	wait, err := services.LoginRetryAfter(ctx, user.Email, c.IP())
+	if err != nil {
+		return false, c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+	if wait > 0 {
+		return false, tooManyAttempts(c, wait)
+	}
+
+	if utils.CheckPasswordHash(password, user.Password) {
+		if err := services.ResetLoginFailures(ctx, user.Email); err != nil {
+			return false, c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+		}
+		return true, nil
+	}
+
+	if event != "" {
+		recordAudit(c, models.AuditEvent{
+			Event:        event,
+			Outcome:      models.AuditFailure,
+			TargetUserID: &user.ID,
+			Metadata:     map[string]string{"reason": "invalid_password"},
+		})
+	}
+
+	lockout, err := services.RecordLoginFailure(ctx, user.Email, c.IP())
+	if err != nil {
+		return false, c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+	if lockout > 0 {
+		auditLockout(c, user.Email, user, lockout)
+		return false, tooManyAttempts(c, lockout)
+	}
+	return false, c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": message})
+}
+
+// accountDisabled responds 403 for accounts an admin has disabled
+func accountDisabled(c *fiber.Ctx) error {
+	return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "This account has been disabled", "code": "account_disabled"})
+}
+
+// accountPendingDeletion responds 403 for accounts awaiting deletion; they can
+// only be brought back through the emailed restore link
+func accountPendingDeletion(c *fiber.Ctx) error {
+	return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "This account is scheduled for deletion, use the link in your email to restore it", "code": "account_pending_deletion"})
+}
+
+// tooManyAttempts responds 429 with a Retry-After header in whole seconds
+func tooManyAttempts(c *fiber.Ctx, wait time.Duration) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	return c.Status(http.StatusTooManyRequests).JSON(fiber.Map{"error": "Too many failed login attempts, please try again later"})
+}
+
+// issueSession starts a new refresh token family for the user, sets the auth
+// cookies and returns the access token
+func issueSession(ctx context.Context, c *fiber.Ctx, user *models.User, method string) (string, error) {
+	// The refresh token family doubles as the session ID carried in the access token
+	familyID := primitive.NewObjectID()
+
+	accessToken, claims, err := utils.GenerateJWT(user.ID.Hex(), familyID.Hex())
+	if err != nil {
+		return "", err
+	}
+
+	refreshToken, _, err := services.IssueRefreshToken(ctx, user.ID, familyID)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := services.CreateSession(ctx, user.ID, familyID, claims.ID, c.Get(fiber.HeaderUserAgent), c.IP()); err != nil {
+		return "", err
+	}
+
+	setAuthCookies(c, accessToken, refreshToken)
+
+	// A new login always gets a fresh CSRF token
+	if _, err := setCSRFCookie(c, ""); err != nil {
+		return "", err
+	}
+
+	recordAudit(c, models.AuditEvent{
+		Event:        models.AuditLogin,
+		Outcome:      models.AuditSuccess,
+		ActorID:      &user.ID,
+		TargetUserID: &user.ID,
+		Metadata:     map[string]string{"method": method},
+	})
+	return accessToken, nil
+}
+
+// setAuthCookies stores the access and refresh tokens in HTTP-only cookies
+func setAuthCookies(c *fiber.Ctx, accessToken, refreshToken string) {
 	c.Cookie(&fiber.Cookie{
 		Name:     "token",
-		Value:    token,
-		Expires:  time.Now().Add(24 * time.Hour), // Set expiration for 1 day
-		HTTPOnly: true,                           // Secure the cookie from JavaScript access
-		Secure:   true,                           // Send only over HTTPS
+		Value:    accessToken,
+		Expires:  time.Now().Add(utils.AccessTokenTTL),
+		HTTPOnly: true, // Secure the cookie from JavaScript access
+		Secure:   true, // Send only over HTTPS
 		SameSite: "Strict",
 	})
 
-	// Send response with user details (excluding password)
-	return c.JSON(fiber.Map{
-		"message": "Login successful",
-		"user": fiber.Map{
-			"id":        user.ID.Hex(),
-			"name":      user.Name,
-			"email":     user.Email,
-			"createdAt": user.CreatedAt,
-			"updatedAt": user.UpdatedAt,
-		},
+	// The refresh token is only ever needed by the /auth endpoints
+	c.Cookie(&fiber.Cookie{
+		Name:     "refresh_token",
+		Value:    refreshToken,
+		Path:     "/auth",
+		Expires:  time.Now().Add(utils.RefreshTokenTTL),
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: "Strict",
 	})
 }
+
+// setCSRFCookie stores token in the csrf_token cookie, generating a new one
+// when token is empty. Unlike the auth cookies it is readable from JavaScript
+// so the frontend can copy it into the X-CSRF-Token header.
+func setCSRFCookie(c *fiber.Ctx, token string) (string, error) {
+	if token == "" {
+		var err error
+		if token, err = utils.GenerateCSRFToken(); err != nil {
+			return "", err
+		}
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     utils.CSRFCookieName,
+		Value:    token,
+		Expires:  time.Now().Add(utils.RefreshTokenTTL),
+		HTTPOnly: false,
+		Secure:   true,
+		SameSite: "Strict",
+	})
+	return token, nil
+}
+
+// clearAuthCookies expires the auth and CSRF cookies on the client
+func clearAuthCookies(c *fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{Name: "token", Value: "", Expires: time.Unix(0, 0), HTTPOnly: true, Secure: true, SameSite: "Strict"})
+	c.Cookie(&fiber.Cookie{Name: "refresh_token", Value: "", Path: "/auth", Expires: time.Unix(0, 0), HTTPOnly: true, Secure: true, SameSite: "Strict"})
+	c.Cookie(&fiber.Cookie{Name: utils.CSRFCookieName, Value: "", Expires: time.Unix(0, 0), Secure: true, SameSite: "Strict"})
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+)
+
+func TestRegisterIgnoresAccountStateInBody(t *testing.T) {
+	if err := utils.LoadPasswordHasher(); err != nil {
+		t.Fatal(err)
+	}
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("forged fields", func(mt *mtest.T) {
+		db := mt.Client.Database("taskapp")
+		config.UsersCollection = db.Collection("users")
+		config.WorkspacesCollection = db.Collection("workspaces")
+		config.WorkspaceMembersCollection = db.Collection("workspace_members")
+		config.AuditEventsCollection = db.Collection("audit_events")
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "taskapp.users", mtest.FirstBatch), // Email is free
+			mtest.CreateSuccessResponse(),                                    // Insert user
+			mtest.CreateCursorResponse(0, "taskapp.workspaces", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(), // Insert workspace
+			mtest.CreateSuccessResponse(), // Insert membership
+			mtest.CreateSuccessResponse(), // Audit event
+		)
+
+		app := fiber.New()
+		app.Post("/auth/register", Register)
+
+		body := `{"name":"Jane Doe","email":"jane@example.com","password":"Correct-Horse-Battery-9",
+			"role":"admin","two_factor_enabled":true,"disabled":true,"email_verified":true}`
+		req := httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusCreated {
+			mt.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusCreated)
+		}
+
+		var inserted bson.Raw
+		for _, event := range mt.GetAllStartedEvents() {
+			if event.CommandName == "insert" && event.Command.Lookup("insert").StringValue() == "users" {
+				inserted = event.Command.Lookup("documents", "0").Document()
+			}
+		}
+		if inserted == nil {
+			mt.Fatal("user was not inserted")
+		}
+		if role := inserted.Lookup("role").StringValue(); role != string(models.RoleMember) {
+			mt.Fatalf("role = %q, want member", role)
+		}
+		for _, field := range []string{"two_factor_enabled", "disabled", "email_verified"} {
+			if value, err := inserted.LookupErr(field); err == nil && value.Boolean() {
+				mt.Fatalf("%s was taken from the request body", field)
+			}
+		}
+	})
+}
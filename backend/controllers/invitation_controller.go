@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"backend/config"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+)
+
+const defaultInvitationDays = 7
+
+// CreateInvitation - Invites someone by email to the active workspace
+func CreateInvitation(c *fiber.Ctx) error {
+	var request struct {
+		Email         string      `json:"email" validate:"required,email"`
+		Role          models.Role `json:"role"`
+		ExpiresInDays int         `json:"expires_in_days" validate:"omitempty,min=1,max=30"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+	if request.Role == "" {
+		request.Role = models.RoleMember
+	}
+	if !request.Role.IsValid() {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid role", "valid_roles": models.Roles})
+	}
+	if request.ExpiresInDays == 0 {
+		request.ExpiresInDays = defaultInvitationDays
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	inviter, err := currentUser(ctx, c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	var workspace models.Workspace
+	if err := config.WorkspacesCollection.FindOne(ctx, bson.M{"_id": c.Locals("workspaceID")}).Decode(&workspace); err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Workspace not found"})
+	}
+
+	ttl := time.Duration(request.ExpiresInDays) * 24 * time.Hour
+	invitation, err := services.CreateInvitation(ctx, &workspace, inviter, request.Email, request.Role, ttl)
+	if err == services.ErrInvitationsNeedFrontend {
+		log.Println("Invitation not sent:", err)
+		return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{"error": "Invitations are not configured on this server"})
+	} else if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to send invitation"})
+	}
+
+	return c.Status(http.StatusCreated).JSON(invitation)
+}
+
+// ListInvitations - Lists pending invitations for the active workspace
+func ListInvitations(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	invitations, err := services.ListPendingInvitations(ctx, c.Locals("workspaceID").(primitive.ObjectID))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch invitations"})
+	}
+
+	return c.JSON(invitations)
+}
+
+// RevokeInvitation - Cancels a pending invitation in the active workspace
+func RevokeInvitation(c *fiber.Ctx) error {
+	invitationID, err := primitive.ObjectIDFromHex(c.Params("invitationID"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid invitation ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	revoked, err := services.RevokeInvitation(ctx, c.Locals("workspaceID").(primitive.ObjectID), invitationID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke invitation"})
+	}
+	if !revoked {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Invitation not found"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Invitation revoked successfully"})
+}
+
+// AcceptInvitation - Joins the invited workspace as the logged-in user
+func AcceptInvitation(c *fiber.Ctx) error {
+	var request struct {
+		Token string `json:"token" validate:"required"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := currentUser(ctx, c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	invitation, err := services.AcceptInvitation(ctx, request.Token, user)
+	switch err {
+	case nil:
+		return c.JSON(fiber.Map{"message": "Invitation accepted", "workspace_id": invitation.WorkspaceID, "role": invitation.Role})
+	case services.ErrInvitationInvalid:
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid, expired or revoked invitation"})
+	case services.ErrInvitationEmailMismatch:
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "This invitation was sent to a different email address"})
+	default:
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to accept invitation"})
+	}
+}
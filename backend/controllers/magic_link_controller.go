@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"backend/services"
+	"backend/utils"
+)
+
+// RequestMagicLink - Emails a one-time login link. The response never reveals
+// whether the address belongs to an account.
+func RequestMagicLink(c *fiber.Ctx) error {
+	var request struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	email := utils.NormalizeEmail(request.Email)
+	ip := c.IP()
+
+	// Send in the background so response time doesn't depend on whether the account exists
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := services.SendMagicLink(ctx, email, ip); err != nil && err != mongo.ErrNoDocuments {
+			log.Println("Failed to send magic link:", err)
+		}
+	}()
+
+	return c.Status(http.StatusAccepted).JSON(fiber.Map{"message": "If an account exists for that email, a sign-in link has been sent"})
+}
+
+// ConsumeMagicLink - Signs in with a magic link token, exactly as a password
+// login would: 2FA still applies and the normal session is issued
+func ConsumeMagicLink(c *fiber.Ctx) error {
+	var request struct {
+		Token string `json:"token" validate:"required"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := services.ConsumeMagicLink(ctx, request.Token)
+	if err == services.ErrMagicLinkInvalid {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired login link"})
+	} else if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to sign in"})
+	}
+
+	return continueLogin(ctx, c, user, "magic_link")
+}
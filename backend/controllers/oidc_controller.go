@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend/services"
+	"backend/utils"
+)
+
+// oidcFlowTTL bounds how long the user may take at the identity provider
+const oidcFlowTTL = 10 * time.Minute
+
+// StartOIDCLogin - Redirects to the identity provider with state, nonce and a PKCE challenge
+func StartOIDCLogin(c *fiber.Ctx) error {
+	cfg, err := services.LoadOIDCConfig()
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Single sign-on is not configured"})
+	}
+
+	state, err1 := utils.GenerateRandomToken(24)
+	nonce, err2 := utils.GenerateRandomToken(24)
+	verifier, err3 := utils.GenerateRandomToken(48)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start sign-on"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	authURL, err := services.OIDCAuthorizationURL(ctx, cfg, state, nonce, verifier)
+	if err != nil {
+		log.Println("OIDC discovery failed:", err)
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{"error": "Identity provider unavailable"})
+	}
+
+	// The flow state rides in a signed, HTTP-only cookie bound to this browser
+	flow, err := utils.GenerateActionTokenWithData(utils.PurposeOIDCLogin, state, map[string]string{
+		"nonce":         nonce,
+		"code_verifier": verifier,
+	}, oidcFlowTTL)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start sign-on"})
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     "oidc_flow",
+		Value:    flow,
+		Path:     "/auth/oidc",
+		Expires:  time.Now().Add(oidcFlowTTL),
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: "Lax", // Must survive the top-level redirect back from the provider
+	})
+
+	return c.Redirect(authURL, http.StatusFound)
+}
+
+// OIDCCallback - Completes the authorization code flow and starts a normal session
+func OIDCCallback(c *fiber.Ctx) error {
+	cfg, err := services.LoadOIDCConfig()
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Single sign-on is not configured"})
+	}
+
+	if providerError := c.Query("error"); providerError != "" {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Sign-on was cancelled or denied", "provider_error": providerError})
+	}
+
+	flow, err := utils.VerifyActionToken(c.Cookies("oidc_flow"), utils.PurposeOIDCLogin)
+	c.Cookie(&fiber.Cookie{Name: "oidc_flow", Value: "", Path: "/auth/oidc", Expires: time.Unix(0, 0), HTTPOnly: true, Secure: true, SameSite: "Lax"})
+	if err != nil || c.Query("state") == "" || flow.Subject != c.Query("state") {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired sign-on state"})
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Missing authorization code"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	claims, err := services.ExchangeOIDCCode(ctx, cfg, code, flow.Data["code_verifier"], flow.Data["nonce"])
+	if err != nil {
+		log.Println("OIDC code exchange failed:", err)
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Sign-on failed"})
+	}
+
+	user, err := services.ResolveOIDCUser(ctx, cfg, claims)
+	switch err {
+	case nil:
+	case services.ErrOIDCEmailNotVerified, services.ErrOIDCUserNotFound, services.ErrOIDCAccountLinked:
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to sign in"})
+	}
+
+	// Single sign-on replaces the password, not the second factor. Browser
+	// flows land back on OIDC_POST_LOGIN_REDIRECT; API callers get the usual JSON.
+	return continueLogin(ctx, c, user, "oidc")
+}
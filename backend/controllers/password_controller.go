@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"backend/config"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+)
+
+// ForgotPassword - Emails a password reset link. The response never reveals
+// whether the address belongs to an account.
+func ForgotPassword(c *fiber.Ctx) error {
+	var request struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	email := utils.NormalizeEmail(request.Email)
+	ip := c.IP()
+
+	// Send in the background so response time doesn't depend on whether the account exists
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		var user models.User
+		if err := config.UsersCollection.FindOne(ctx, bson.M{"email": email}).Decode(&user); err != nil {
+			return
+		}
+		if err := services.StartPasswordReset(ctx, &user, ip); err != nil {
+			log.Println("Failed to start password reset:", err)
+		}
+	}()
+
+	return c.Status(http.StatusAccepted).JSON(fiber.Map{"message": "If an account exists for that email, a password reset link has been sent"})
+}
+
+// ResetPassword - Sets a new password from a reset token and signs the user out everywhere
+func ResetPassword(c *fiber.Ctx) error {
+	var request struct {
+		Token    string `json:"token" validate:"required"`
+		Password string `json:"password" validate:"required"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userID, err := services.FindPasswordReset(ctx, request.Token)
+	if err == services.ErrPasswordResetInvalid {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired reset token"})
+	} else if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset password"})
+	}
+
+	// Check the policy before consuming so a rejected password doesn't burn the link
+	var user models.User
+	if err := config.UsersCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired reset token"})
+	}
+	if errs := utils.ValidatePassword("password", request.Password, user.Email, user.Name); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	if _, err := services.ConsumePasswordReset(ctx, request.Token); err == services.ErrPasswordResetInvalid {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired reset token"})
+	} else if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset password"})
+	}
+
+	hashedPassword, err := utils.HashPassword(request.Password)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to hash password"})
+	}
+
+	_, err = config.UsersCollection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"password": hashedPassword, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset password"})
+	}
+
+	// Whoever knew the old password must not stay signed in
+	if err := services.RevokeAllUserTokens(ctx, userID); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke existing sessions"})
+	}
+
+	recordAudit(c, models.AuditEvent{Event: models.AuditPasswordReset, Outcome: models.AuditSuccess, ActorID: &userID, TargetUserID: &userID})
+	return c.JSON(fiber.Map{"message": "Password reset successfully"})
+}
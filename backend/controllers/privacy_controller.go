@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"backend/services"
+	"backend/utils"
+)
+
+// ExportMyData - Streams a ZIP archive of everything stored about the caller
+func ExportMyData(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := currentUser(ctx, c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	filename := fmt.Sprintf("account-export-%s.zip", time.Now().Format("2006-01-02"))
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	// The archive is written straight to the connection; once streaming has
+	// started an error can only be logged
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+
+		if err := services.WriteUserExport(ctx, user, w); err != nil {
+			log.Println("Failed to write data export:", err)
+		}
+		if err := w.Flush(); err != nil {
+			log.Println("Failed to flush data export:", err)
+		}
+	})
+
+	return nil
+}
+
+// reauthWindow is how recently a login must have happened to stand in for
+// re-entering the password
+const reauthWindow = 10 * time.Minute
+
+// DeleteMe - Schedules the caller's account for deletion after re-checking the
+// password or, for accounts without one, a recent login. The caller is signed
+// out everywhere immediately.
+func DeleteMe(c *fiber.Ctx) error {
+	var request struct {
+		Password string `json:"password"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := currentUser(ctx, c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if user.Password != "" {
+		if request.Password == "" {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Password is required"})
+		}
+		if ok, err := confirmPassword(ctx, c, user, request.Password, "", "Password is incorrect"); !ok {
+			return err
+		}
+	} else {
+		// SSO and passwordless accounts prove themselves with a fresh login
+		fresh, err := recentlyAuthenticated(ctx, c, user.ID)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete account"})
+		}
+		if !fresh {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{
+				"error": "Log in again to delete your account",
+				"code":  "reauthentication_required",
+			})
+		}
+	}
+
+	scheduledFor, err := services.RequestAccountDeletion(ctx, user)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete account"})
+	}
+
+	clearAuthCookies(c)
+	return c.Status(http.StatusAccepted).JSON(fiber.Map{
+		"message":       "Your account is scheduled for deletion. Check your email for a link to restore it.",
+		"scheduled_for": scheduledFor,
+	})
+}
+
+// recentlyAuthenticated reports whether the caller's session began with a
+// login within reauthWindow
+func recentlyAuthenticated(ctx context.Context, c *fiber.Ctx, userID primitive.ObjectID) (bool, error) {
+	sessionID, err := primitive.ObjectIDFromHex(c.Locals("claims").(*utils.Claims).SessionID)
+	if err != nil {
+		return false, nil
+	}
+	return services.SessionAuthenticatedSince(ctx, userID, sessionID, time.Now().Add(-reauthWindow))
+}
+
+// RestoreAccount - Cancels a scheduled deletion from the emailed link
+func RestoreAccount(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	switch err := services.RestoreAccount(ctx, c.Query("token")); err {
+	case nil:
+		return c.JSON(fiber.Map{"message": "Your account has been restored, you can log in again"})
+	case services.ErrAccountRestoreInvalid:
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired restore link"})
+	default:
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to restore account"})
+	}
+}
@@ -0,0 +1,318 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+)
+
+// CreateServiceAccount - Creates a service account in the active workspace
+func CreateServiceAccount(c *fiber.Ctx) error {
+	var request struct {
+		Name        string      `json:"name" validate:"required,max=100"`
+		Description string      `json:"description" validate:"max=500"`
+		Role        models.Role `json:"role" validate:"required"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	request.Name = strings.TrimSpace(request.Name)
+	request.Description = strings.TrimSpace(request.Description)
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+	if !validServiceAccountRole(request.Role) {
+		return invalidServiceAccountRole(c)
+	}
+
+	creatorID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	account := &models.ServiceAccount{
+		WorkspaceID: c.Locals("workspaceID").(primitive.ObjectID),
+		Name:        request.Name,
+		Description: request.Description,
+		Role:        request.Role,
+		CreatedBy:   creatorID,
+	}
+	if err := services.CreateServiceAccount(ctx, account); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create service account"})
+	}
+
+	recordAudit(c, models.AuditEvent{
+		Event:    models.AuditServiceAccountCreate,
+		Outcome:  models.AuditSuccess,
+		Metadata: serviceAccountAuditMetadata(account, map[string]string{"role": string(account.Role)}),
+	})
+	return c.Status(http.StatusCreated).JSON(account)
+}
+
+// ListServiceAccounts - Lists the service accounts of the active workspace
+func ListServiceAccounts(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	accounts, err := services.ListServiceAccounts(ctx, c.Locals("workspaceID").(primitive.ObjectID))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch service accounts"})
+	}
+
+	return c.JSON(accounts)
+}
+
+// UpdateServiceAccount - Renames, re-roles, disables or enables a service account.
+// Omitted fields are left unchanged.
+func UpdateServiceAccount(c *fiber.Ctx) error {
+	var request struct {
+		Name        *string      `json:"name" validate:"omitnil,min=1,max=100"`
+		Description *string      `json:"description" validate:"omitnil,max=500"`
+		Role        *models.Role `json:"role"`
+		Disabled    *bool        `json:"disabled"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if request.Name != nil {
+		*request.Name = strings.TrimSpace(*request.Name)
+	}
+	if request.Description != nil {
+		*request.Description = strings.TrimSpace(*request.Description)
+	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	changes := bson.M{}
+	metadata := map[string]string{}
+	if request.Name != nil {
+		changes["name"] = *request.Name
+	}
+	if request.Description != nil {
+		changes["description"] = *request.Description
+	}
+	if request.Role != nil {
+		if !validServiceAccountRole(*request.Role) {
+			return invalidServiceAccountRole(c)
+		}
+		changes["role"] = *request.Role
+		metadata["role"] = string(*request.Role)
+	}
+	if request.Disabled != nil {
+		changes["disabled"] = *request.Disabled
+		metadata["disabled"] = strconv.FormatBool(*request.Disabled)
+	}
+	if len(changes) == 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Nothing to update"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	account, err := workspaceServiceAccount(ctx, c)
+	if err != nil {
+		return serviceAccountLookupFailed(c, err)
+	}
+
+	err = services.UpdateServiceAccount(ctx, account.WorkspaceID, account.ID, changes)
+	if err == services.ErrServiceAccountNotFound {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Service account not found"})
+	} else if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update service account"})
+	}
+
+	recordAudit(c, models.AuditEvent{
+		Event:    models.AuditServiceAccountUpdate,
+		Outcome:  models.AuditSuccess,
+		Metadata: serviceAccountAuditMetadata(account, metadata),
+	})
+	return c.JSON(fiber.Map{"message": "Service account updated successfully"})
+}
+
+// DeleteServiceAccount - Deletes a service account and all of its keys
+func DeleteServiceAccount(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	account, err := workspaceServiceAccount(ctx, c)
+	if err != nil {
+		return serviceAccountLookupFailed(c, err)
+	}
+
+	err = services.DeleteServiceAccount(ctx, account.WorkspaceID, account.ID)
+	if err == services.ErrServiceAccountNotFound {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Service account not found"})
+	} else if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete service account"})
+	}
+
+	recordAudit(c, models.AuditEvent{
+		Event:    models.AuditServiceAccountDelete,
+		Outcome:  models.AuditSuccess,
+		Metadata: serviceAccountAuditMetadata(account, nil),
+	})
+	return c.JSON(fiber.Map{"message": "Service account deleted successfully"})
+}
+
+// CreateServiceAccountKey - Creates an API key; the secret is only returned in this response
+func CreateServiceAccountKey(c *fiber.Ctx) error {
+	var request struct {
+		Name          string `json:"name" validate:"required,max=100"`
+		ExpiresInDays int    `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	request.Name = strings.TrimSpace(request.Name)
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+	if request.ExpiresInDays == 0 {
+		request.ExpiresInDays = defaultTokenLifetimeDays
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	account, err := workspaceServiceAccount(ctx, c)
+	if err != nil {
+		return serviceAccountLookupFailed(c, err)
+	}
+
+	expiresAt := time.Now().Add(time.Duration(request.ExpiresInDays) * 24 * time.Hour)
+	secret, key, err := services.CreateServiceAccountKey(ctx, account.ID, request.Name, expiresAt)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create key"})
+	}
+
+	recordAudit(c, models.AuditEvent{
+		Event:    models.AuditTokenCreate,
+		Outcome:  models.AuditSuccess,
+		Metadata: serviceAccountAuditMetadata(account, map[string]string{"token_id": key.ID.Hex(), "name": key.Name}),
+	})
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"message": "Store this key now, it will not be shown again",
+		"key":     secret,
+		"details": key,
+	})
+}
+
+// ListServiceAccountKeys - Lists a service account's keys without their secrets
+func ListServiceAccountKeys(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	account, err := workspaceServiceAccount(ctx, c)
+	if err != nil {
+		return serviceAccountLookupFailed(c, err)
+	}
+
+	keys, err := services.ListServiceAccountKeys(ctx, account.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch keys"})
+	}
+
+	return c.JSON(keys)
+}
+
+// RevokeServiceAccountKey - Revokes one of a service account's keys
+func RevokeServiceAccountKey(c *fiber.Ctx) error {
+	keyID, err := primitive.ObjectIDFromHex(c.Params("keyID"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid key ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	account, err := workspaceServiceAccount(ctx, c)
+	if err != nil {
+		return serviceAccountLookupFailed(c, err)
+	}
+
+	revoked, err := services.RevokeServiceAccountKey(ctx, account.ID, keyID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke key"})
+	}
+	if !revoked {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Key not found"})
+	}
+
+	recordAudit(c, models.AuditEvent{
+		Event:    models.AuditTokenRevoke,
+		Outcome:  models.AuditSuccess,
+		Metadata: serviceAccountAuditMetadata(account, map[string]string{"token_id": keyID.Hex()}),
+	})
+	return c.JSON(fiber.Map{"message": "Key revoked successfully"})
+}
+
+// workspaceServiceAccount loads the service account named in the path from the active workspace
+func workspaceServiceAccount(ctx context.Context, c *fiber.Ctx) (*models.ServiceAccount, error) {
+	accountID, err := primitive.ObjectIDFromHex(c.Params("serviceAccountID"))
+	if err != nil {
+		return nil, errInvalidServiceAccountID
+	}
+
+	return services.GetServiceAccount(ctx, c.Locals("workspaceID").(primitive.ObjectID), accountID)
+}
+
+var errInvalidServiceAccountID = errors.New("invalid service account ID")
+
+// serviceAccountLookupFailed maps a workspaceServiceAccount error to a response
+func serviceAccountLookupFailed(c *fiber.Ctx, err error) error {
+	switch err {
+	case errInvalidServiceAccountID:
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid service account ID"})
+	case services.ErrServiceAccountNotFound:
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Service account not found"})
+	default:
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch service account"})
+	}
+}
+
+// validServiceAccountRole reports whether a service account may hold role.
+// Workspace administration stays with people.
+func validServiceAccountRole(role models.Role) bool {
+	return role.IsValid() && role != models.RoleAdmin
+}
+
+func invalidServiceAccountRole(c *fiber.Ctx) error {
+	roles := []models.Role{}
+	for _, role := range models.Roles {
+		if validServiceAccountRole(role) {
+			roles = append(roles, role)
+		}
+	}
+	return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid role", "valid_roles": roles})
+}
+
+// serviceAccountAuditMetadata identifies the service account in an audit event
+func serviceAccountAuditMetadata(account *models.ServiceAccount, extra map[string]string) map[string]string {
+	metadata := map[string]string{
+		"workspace_id":       account.WorkspaceID.Hex(),
+		"service_account_id": account.ID.Hex(),
+	}
+	for key, value := range extra {
+		metadata[key] = value
+	}
+	return metadata
+}
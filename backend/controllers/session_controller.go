@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+)
+
+// ListSessions - Lists the caller's active sessions, flagging the one making the request
+func ListSessions(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sessions, err := services.ListSessions(ctx, userID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch sessions"})
+	}
+
+	currentID := c.Locals("claims").(*utils.Claims).SessionID
+	response := make([]fiber.Map, 0, len(sessions))
+	for _, s := range sessions {
+		response = append(response, fiber.Map{
+			"id":           s.ID,
+			"user_agent":   s.UserAgent,
+			"ip":           s.IP,
+			"last_seen_ip": s.LastSeenIP,
+			"created_at":   s.CreatedAt,
+			"last_seen_at": s.LastSeenAt,
+			"expires_at":   s.ExpiresAt,
+			"current":      s.ID.Hex() == currentID,
+		})
+	}
+
+	return c.JSON(response)
+}
+
+// RevokeSession - Signs out one of the caller's sessions
+func RevokeSession(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	sessionID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid session ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	revoked, err := services.RevokeSession(ctx, userID, sessionID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke session"})
+	}
+	if !revoked {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Session not found"})
+	}
+
+	if sessionID.Hex() == c.Locals("claims").(*utils.Claims).SessionID {
+		clearAuthCookies(c)
+	}
+
+	recordAudit(c, models.AuditEvent{
+		Event:        models.AuditSessionRevoke,
+		Outcome:      models.AuditSuccess,
+		TargetUserID: &userID,
+		Metadata:     map[string]string{"session_id": sessionID.Hex()},
+	})
+
+	return c.JSON(fiber.Map{"message": "Session revoked successfully"})
+}
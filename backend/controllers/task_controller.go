@@ -3,6 +3,7 @@ package controllers
 import (
 	"context"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -12,6 +13,8 @@ import (
 
 	"backend/config"
 	"backend/models"
+	"backend/services"
+	"backend/utils"
 )
 
 // CreateTask - Creates a new task
@@ -21,15 +24,37 @@ func CreateTask(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
+	creatorID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
 	task.ID = primitive.NewObjectID()
+	task.WorkspaceID = c.Locals("workspaceID").(primitive.ObjectID)
+	task.CreatedBy = creatorID
+	task.CreatedByType = actorType(c)
+	task.UpdatedBy = primitive.NilObjectID
+	task.UpdatedByType = ""
+	task.Comments = nil
 	task.Status = models.Pending
 	task.CreatedAt = time.Now()
 	task.UpdatedAt = time.Now()
+	if task.Priority == "" {
+		task.Priority = models.Medium
+	}
+
+	if errs := utils.ValidateStruct(task); errs != nil {
+		return validationFailed(c, errs)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	_, err := config.TasksCollection.InsertOne(ctx, task)
+	if ok, err := checkAssignees(ctx, c, task.AssignedTo); !ok {
+		return err
+	}
+
+	_, err = config.TasksCollection.InsertOne(ctx, task)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create task"})
 	}
@@ -37,14 +62,21 @@ func CreateTask(c *fiber.Ctx) error {
 	return c.Status(http.StatusCreated).JSON(task)
 }
 
-// GetAllTasks - Fetches all tasks
+// GetAllTasks - Fetches the tasks in the active workspace the caller may see:
+// all of them for roles with read-all, otherwise those they created or are
+// assigned to
 func GetAllTasks(c *fiber.Ctx) error {
+	filter, err := taskScopeFilter(c, models.PermTaskReadAll)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
 	var tasks []models.Task
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	cursor, err := config.TasksCollection.Find(ctx, bson.M{})
+	cursor, err := config.TasksCollection.Find(ctx, filter)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch tasks"})
 	}
@@ -61,7 +93,7 @@ func GetAllTasks(c *fiber.Ctx) error {
 	return c.JSON(tasks)
 }
 
-// GetMyTasks - Fetches tasks assigned to the logged-in user
+// GetMyTasks - Fetches tasks in the active workspace assigned to the logged-in user
 func GetMyTasks(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(string) // Extract user ID from middleware
 
@@ -75,7 +107,7 @@ func GetMyTasks(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	cursor, err := config.TasksCollection.Find(ctx, bson.M{"assigned_to": objID})
+	cursor, err := config.TasksCollection.Find(ctx, bson.M{"workspace_id": c.Locals("workspaceID"), "assigned_to": objID})
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch tasks"})
 	}
@@ -101,12 +133,18 @@ func GetTaskByID(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid task ID"})
 	}
 
+	filter, err := taskAccessFilter(c, objID)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
 	var task models.Task
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	err = config.TasksCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&task)
+	// Tasks the caller may not see are reported as missing so IDs don't leak
+	err = config.TasksCollection.FindOne(ctx, filter).Decode(&task)
 	if err == mongo.ErrNoDocuments {
 		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Task not found"})
 	} else if err != nil {
@@ -129,20 +167,48 @@ func UpdateTask(c *fiber.Ctx) error {
 	if err := c.BodyParser(&updateData); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request data"})
 	}
+	if errs := utils.ValidateStruct(updateData); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	filter, err := taskAccessFilter(c, objID)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	editorID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
 
+	// Ownership and workspace can't be reassigned through an update, and
+	// comments are only added through AddTaskComment so authors can't be forged
+	updateData.CreatedBy = primitive.NilObjectID
+	updateData.CreatedByType = ""
+	updateData.WorkspaceID = primitive.NilObjectID
+	updateData.Comments = nil
+	updateData.UpdatedBy = editorID
+	updateData.UpdatedByType = actorType(c)
 	updateData.UpdatedAt = time.Now()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if ok, err := checkAssignees(ctx, c, updateData.AssignedTo); !ok {
+		return err
+	}
+
 	update := bson.M{
 		"$set": updateData,
 	}
 
-	_, err = config.TasksCollection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	result, err := config.TasksCollection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update task"})
 	}
+	if result.MatchedCount == 0 {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Task not found"})
+	}
 
 	return c.JSON(fiber.Map{"message": "Task updated successfully"})
 }
@@ -157,27 +223,99 @@ func UpdateTaskStatus(c *fiber.Ctx) error {
 	}
 
 	var statusUpdate struct {
-		Status models.TaskStatus `json:"status"`
+		Status models.TaskStatus `json:"status" validate:"required,oneof=pending in_progress completed"`
 	}
 	if err := c.BodyParser(&statusUpdate); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request data"})
 	}
+	if errs := utils.ValidateStruct(statusUpdate); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	filter, err := taskAccessFilter(c, objID)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	editorID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	update := bson.M{
-		"$set": bson.M{"status": statusUpdate.Status, "updated_at": time.Now()},
+		"$set": bson.M{
+			"status":          statusUpdate.Status,
+			"updated_by":      editorID,
+			"updated_by_type": actorType(c),
+			"updated_at":      time.Now(),
+		},
 	}
 
-	_, err = config.TasksCollection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	result, err := config.TasksCollection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update task status"})
 	}
+	if result.MatchedCount == 0 {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Task not found"})
+	}
 
 	return c.JSON(fiber.Map{"message": "Task status updated successfully"})
 }
 
+// AddTaskComment - Adds a comment to a task, authored by the caller
+func AddTaskComment(c *fiber.Ctx) error {
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid task ID"})
+	}
+
+	var request struct {
+		Text string `json:"text"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request data"})
+	}
+
+	authorID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	comment := models.Comment{
+		UserID:     authorID,
+		AuthorType: actorType(c),
+		Text:       strings.TrimSpace(request.Text),
+		CreatedAt:  time.Now(),
+	}
+	if errs := utils.ValidateStruct(comment); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	filter, err := taskAccessFilter(c, objID)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.TasksCollection.UpdateOne(ctx, filter, bson.M{
+		"$push": bson.M{"comments": comment},
+		"$set":  bson.M{"updated_at": comment.CreatedAt},
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to add comment"})
+	}
+	if result.MatchedCount == 0 {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Task not found"})
+	}
+
+	return c.Status(http.StatusCreated).JSON(comment)
+}
+
 // DeleteTask - Deletes a task by ID
 func DeleteTask(c *fiber.Ctx) error {
 	taskID := c.Params("id")
@@ -187,13 +325,83 @@ func DeleteTask(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid task ID"})
 	}
 
+	filter, err := taskAccessFilter(c, objID)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	_, err = config.TasksCollection.DeleteOne(ctx, bson.M{"_id": objID})
+	result, err := config.TasksCollection.DeleteOne(ctx, filter)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete task"})
 	}
+	if result.DeletedCount == 0 {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Task not found"})
+	}
 
 	return c.JSON(fiber.Map{"message": "Task deleted successfully"})
 }
+
+// taskAccessFilter matches the task only inside the active workspace, and only
+// if the caller created it, is assigned to it, or holds a role that may manage
+// any task
+func taskAccessFilter(c *fiber.Ctx, taskID primitive.ObjectID) (bson.M, error) {
+	filter, err := taskScopeFilter(c, models.PermTaskManageAny)
+	if err != nil {
+		return nil, err
+	}
+	filter["_id"] = taskID
+	return filter, nil
+}
+
+// taskScopeFilter matches tasks in the active workspace. Unless the caller's
+// role grants unrestricted, only tasks they created or are assigned to match.
+func taskScopeFilter(c *fiber.Ctx, unrestricted models.Permission) (bson.M, error) {
+	workspaceID := c.Locals("workspaceID").(primitive.ObjectID)
+
+	role, _ := c.Locals("role").(models.Role)
+	if role.Can(unrestricted) {
+		return bson.M{"workspace_id": workspaceID}, nil
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	return bson.M{
+		"workspace_id": workspaceID,
+		"$or": []bson.M{
+			{"created_by": userID},
+			{"assigned_to": userID},
+		},
+	}, nil
+}
+
+// checkAssignees responds with a validation error unless every assignee is a
+// member of the active workspace. It returns false once a response was sent.
+func checkAssignees(ctx context.Context, c *fiber.Ctx, assignees []primitive.ObjectID) (bool, error) {
+	missing, err := services.WorkspaceNonMembers(ctx, c.Locals("workspaceID").(primitive.ObjectID), assignees)
+	if err != nil {
+		return false, c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to check assignees"})
+	}
+	if len(missing) > 0 {
+		return false, validationFailed(c, []utils.FieldError{{
+			Field:   "assigned_to",
+			Rule:    "workspace_member",
+			Param:   missing[0].Hex(),
+			Message: "Assignees must be members of the workspace",
+		}})
+	}
+	return true, nil
+}
+
+// actorType reports whether the caller is a user or a service account
+func actorType(c *fiber.Ctx) models.ActorType {
+	if t, ok := c.Locals("actorType").(models.ActorType); ok {
+		return t
+	}
+	return models.ActorUser
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"backend/config"
+	"backend/models"
+)
+
+func TestGetAllTasksScopesByRole(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		role       models.Role
+		restricted bool
+	}{
+		{role: models.RoleAdmin, restricted: false},
+		{role: models.RoleManager, restricted: false},
+		{role: models.RoleMember, restricted: true},
+		{role: models.RoleViewer, restricted: true},
+	}
+
+	for _, tt := range tests {
+		mt.Run(string(tt.role), func(mt *mtest.T) {
+			config.TasksCollection = mt.Client.Database("taskapp").Collection("tasks")
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "taskapp.tasks", mtest.FirstBatch))
+
+			userID, workspaceID := primitive.NewObjectID(), primitive.NewObjectID()
+			app := taskTestApp(http.MethodGet, "/tasks", userID, workspaceID, tt.role, GetAllTasks)
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/tasks", nil))
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if resp.StatusCode != http.StatusOK {
+				mt.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+
+			filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+			if filter.Lookup("workspace_id").ObjectID() != workspaceID {
+				mt.Fatalf("filter not scoped to the workspace: %v", filter)
+			}
+			if _, err := filter.LookupErr("$or"); (err == nil) != tt.restricted {
+				mt.Fatalf("restricted = %v, want %v: %v", err == nil, tt.restricted, filter)
+			}
+		})
+	}
+}
+
+// taskTestApp serves handler with the locals WorkspaceContext would set
+func taskTestApp(method, path string, userID, workspaceID primitive.ObjectID, role models.Role, handler fiber.Handler) *fiber.App {
+	app := fiber.New()
+	app.Add(method, path, func(c *fiber.Ctx) error {
+		c.Locals("userID", userID.Hex())
+		c.Locals("workspaceID", workspaceID)
+		c.Locals("role", role)
+		return c.Next()
+	}, handler)
+	return app
+}
+
+func TestUpdateTaskStatusStoresEditorAsObjectID(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("status", func(mt *mtest.T) {
+		config.TasksCollection = mt.Client.Database("taskapp").Collection("tasks")
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		userID := primitive.NewObjectID()
+		app := taskTestApp(http.MethodPatch, "/tasks/:id/status", userID, primitive.NewObjectID(), models.RoleMember, UpdateTaskStatus)
+
+		req := httptest.NewRequest(http.MethodPatch, "/tasks/"+primitive.NewObjectID().Hex()+"/status", strings.NewReader(`{"status":"completed"}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			mt.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+
+		updatedBy := mt.GetStartedEvent().Command.Lookup("updates", "0", "u", "$set", "updated_by")
+		if id, ok := updatedBy.ObjectIDOK(); !ok || id != userID {
+			mt.Fatalf("updated_by = %v, want ObjectID %v", updatedBy, userID)
+		}
+	})
+}
+
+func TestCreateTaskRejectsAssigneesOutsideWorkspace(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("outsider", func(mt *mtest.T) {
+		db := mt.Client.Database("taskapp")
+		config.TasksCollection = db.Collection("tasks")
+		config.WorkspaceMembersCollection = db.Collection("workspace_members")
+		// Nobody assigned is a member
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "values", Value: bson.A{}}})
+
+		app := taskTestApp(http.MethodPost, "/tasks", primitive.NewObjectID(), primitive.NewObjectID(), models.RoleMember, CreateTask)
+
+		body := `{"title":"Write report","assigned_to":["` + primitive.NewObjectID().Hex() + `"]}`
+		req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			mt.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusUnprocessableEntity)
+		}
+		for _, event := range mt.GetAllStartedEvents() {
+			if event.CommandName == "insert" {
+				mt.Fatal("task was inserted")
+			}
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+)
+
+const defaultTokenLifetimeDays = 30
+
+// CreatePersonalAccessToken - Creates a PAT; the secret is only returned in this response
+func CreatePersonalAccessToken(c *fiber.Ctx) error {
+	var request struct {
+		Name          string   `json:"name" validate:"required,max=100"`
+		Scopes        []string `json:"scopes" validate:"required,min=1"`
+		ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	request.Name = strings.TrimSpace(request.Name)
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+	for _, scope := range request.Scopes {
+		if !isValidScope(scope) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Unknown scope: " + scope, "valid_scopes": models.PersonalAccessTokenScopes})
+		}
+	}
+	if request.ExpiresInDays == 0 {
+		request.ExpiresInDays = defaultTokenLifetimeDays
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	expiresAt := time.Now().Add(time.Duration(request.ExpiresInDays) * 24 * time.Hour)
+	secret, token, err := services.CreatePersonalAccessToken(ctx, userID, request.Name, request.Scopes, expiresAt)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create token"})
+	}
+
+	recordAudit(c, models.AuditEvent{
+		Event:        models.AuditTokenCreate,
+		Outcome:      models.AuditSuccess,
+		TargetUserID: &userID,
+		Metadata:     map[string]string{"token_id": token.ID.Hex(), "name": token.Name, "scopes": strings.Join(token.Scopes, " ")},
+	})
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"message": "Store this token now, it will not be shown again",
+		"token":   secret,
+		"details": token,
+	})
+}
+
+// ListPersonalAccessTokens - Lists the caller's tokens without their secrets
+func ListPersonalAccessTokens(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tokens, err := services.ListPersonalAccessTokens(ctx, userID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch tokens"})
+	}
+
+	return c.JSON(tokens)
+}
+
+// RevokePersonalAccessToken - Revokes one of the caller's tokens
+func RevokePersonalAccessToken(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	tokenID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid token ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	revoked, err := services.RevokePersonalAccessToken(ctx, userID, tokenID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke token"})
+	}
+	if !revoked {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Token not found"})
+	}
+
+	recordAudit(c, models.AuditEvent{
+		Event:        models.AuditTokenRevoke,
+		Outcome:      models.AuditSuccess,
+		TargetUserID: &userID,
+		Metadata:     map[string]string{"token_id": tokenID.Hex()},
+	})
+	return c.JSON(fiber.Map{"message": "Token revoked successfully"})
+}
+
+func isValidScope(scope string) bool {
+	for _, s := range models.PersonalAccessTokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
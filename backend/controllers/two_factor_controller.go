@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"backend/config"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+)
+
+// EnrollTwoFactor - Generates a pending TOTP secret and the otpauth URI for a QR code
+func EnrollTwoFactor(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := currentUser(ctx, c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	}
+	if user.TwoFactorEnabled {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate secret"})
+	}
+
+	_, err = config.UsersCollection.UpdateOne(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{"totp_pending_secret": secret, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start enrolment"})
+	}
+
+	uri := utils.TOTPURI(services.TwoFactorIssuer(), user.Email, secret)
+	return c.JSON(fiber.Map{
+		"secret":      secret,
+		"otpauth_uri": uri,
+		"qr_payload":  uri,
+	})
+}
+
+// ConfirmTwoFactor - Enables 2FA once the user proves their app produces valid codes
+func ConfirmTwoFactor(c *fiber.Ctx) error {
+	var request struct {
+		Code string `json:"code" validate:"required"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := currentUser(ctx, c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	}
+	if user.TwoFactorEnabled {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+	}
+	if user.TOTPPendingSecret == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Start enrolment first"})
+	}
+
+	step, ok := utils.ValidateTOTP(user.TOTPPendingSecret, request.Code, time.Now())
+	if !ok {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid verification code"})
+	}
+
+	codes, err := utils.GenerateRecoveryCodes(services.RecoveryCodeCount)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate recovery codes"})
+	}
+
+	_, err = config.UsersCollection.UpdateOne(ctx,
+		bson.M{"_id": user.ID, "totp_pending_secret": user.TOTPPendingSecret},
+		bson.M{
+			"$set": bson.M{
+				"two_factor_enabled": true,
+				"totp_secret":        user.TOTPPendingSecret,
+				"totp_last_step":     step,
+				"recovery_codes":     services.HashRecoveryCodes(codes),
+				"updated_at":         time.Now(),
+			},
+			"$unset": bson.M{"totp_pending_secret": ""},
+		},
+	)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to enable two-factor authentication"})
+	}
+
+	recordAudit(c, models.AuditEvent{Event: models.AuditTwoFactorEnable, Outcome: models.AuditSuccess, TargetUserID: &user.ID})
+
+	// Recovery codes are only ever shown here
+	return c.JSON(fiber.Map{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor - Turns 2FA off after re-checking the password and a second factor
+func DisableTwoFactor(c *fiber.Ctx) error {
+	var request struct {
+		Password     string `json:"password" validate:"required"`
+		Code         string `json:"code" validate:"required_without=RecoveryCode"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := currentUser(ctx, c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	}
+	if !user.TwoFactorEnabled {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Two-factor authentication is not enabled"})
+	}
+
+	if ok, err := confirmPassword(ctx, c, user, request.Password, models.AuditTwoFactorDisable, "Invalid password"); !ok {
+		return err
+	}
+
+	ok, err := services.VerifySecondFactor(ctx, user, request.Code, request.RecoveryCode, time.Now())
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify code"})
+	}
+	if !ok {
+		// Code guesses share the password lockout budget, as at login
+		lockout, err := services.RecordLoginFailure(ctx, user.Email, c.IP())
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+		}
+		if lockout > 0 {
+			auditLockout(c, user.Email, user, lockout)
+			return tooManyAttempts(c, lockout)
+		}
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid verification code"})
+	}
+
+	_, err = config.UsersCollection.UpdateOne(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{
+			"$set":   bson.M{"two_factor_enabled": false, "updated_at": time.Now()},
+			"$unset": bson.M{"totp_secret": "", "totp_pending_secret": "", "totp_last_step": "", "recovery_codes": ""},
+		},
+	)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to disable two-factor authentication"})
+	}
+
+	recordAudit(c, models.AuditEvent{Event: models.AuditTwoFactorDisable, Outcome: models.AuditSuccess, TargetUserID: &user.ID})
+	return c.JSON(fiber.Map{"message": "Two-factor authentication disabled"})
+}
+
+// VerifyTwoFactorLogin - Second login step: exchanges a challenge token and a
+// TOTP or recovery code for a session
+func VerifyTwoFactorLogin(c *fiber.Ctx) error {
+	var request struct {
+		ChallengeToken string `json:"challenge_token" validate:"required"`
+		Code           string `json:"code" validate:"required_without=RecoveryCode"`
+		RecoveryCode   string `json:"recovery_code"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	claims, err := utils.VerifyActionToken(request.ChallengeToken, utils.PurposeTwoFactorChallenge)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired challenge, please log in again"})
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.Subject)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired challenge, please log in again"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	if err := config.UsersCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired challenge, please log in again"})
+	}
+	// The account may have been disabled since the password step
+	if user.Disabled {
+		auditLoginFailure(c, user.Email, &user, "account_disabled")
+		return accountDisabled(c)
+	}
+
+	// Second factor guesses share the password lockout budget
+	wait, err := services.LoginRetryAfter(ctx, user.Email, c.IP())
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+	if wait > 0 {
+		auditLoginFailure(c, user.Email, &user, "locked_out")
+		return tooManyAttempts(c, wait)
+	}
+
+	ok, err := services.VerifySecondFactor(ctx, &user, request.Code, request.RecoveryCode, time.Now())
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify code"})
+	}
+	if !ok {
+		auditLoginFailure(c, user.Email, &user, "invalid_second_factor")
+
+		lockout, err := services.RecordLoginFailure(ctx, user.Email, c.IP())
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+		}
+		if lockout > 0 {
+			auditLockout(c, user.Email, &user, lockout)
+			return tooManyAttempts(c, lockout)
+		}
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid verification code"})
+	}
+
+	if err := services.ResetLoginFailures(ctx, user.Email); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+
+	return completeLogin(ctx, c, &user, "two_factor")
+}
+
+// currentUser loads the authenticated user set by AuthMiddleware
+func currentUser(ctx context.Context, c *fiber.Ctx) (*models.User, error) {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := config.UsersCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
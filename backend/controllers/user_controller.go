@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend/config"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+)
+
+// GetMe - Returns the caller's profile
+func GetMe(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := currentUser(ctx, c)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	return c.JSON(userProfile(user))
+}
+
+// UpdateMe - Updates the caller's name, avatar URL, timezone and locale.
+// Omitted fields are left unchanged; an empty string clears an optional field.
+func UpdateMe(c *fiber.Ctx) error {
+	var request struct {
+		Name      *string `json:"name" validate:"omitnil,min=3,max=50"`
+		AvatarURL *string `json:"avatar_url" validate:"omitnil,http_url,max=2048"`
+		Timezone  *string `json:"timezone" validate:"omitnil,timezone,ne=Local"`
+		Locale    *string `json:"locale" validate:"omitnil,bcp47_language_tag"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	set := bson.M{}
+	unset := bson.M{}
+
+	// Optional fields are cleared by sending an empty string; only the
+	// remaining values go through validation
+	clearIfEmpty := func(field **string, key string) {
+		if *field != nil && **field == "" {
+			unset[key] = ""
+			*field = nil
+		}
+	}
+	clearIfEmpty(&request.AvatarURL, "avatar_url")
+	clearIfEmpty(&request.Timezone, "timezone")
+	clearIfEmpty(&request.Locale, "locale")
+
+	if request.Name != nil {
+		*request.Name = strings.TrimSpace(*request.Name)
+	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	values := map[string]*string{
+		"name":       request.Name,
+		"avatar_url": request.AvatarURL,
+		"timezone":   request.Timezone,
+		"locale":     request.Locale,
+	}
+	for key, value := range values {
+		if value != nil {
+			set[key] = *value
+		}
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	set["updated_at"] = time.Now()
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	var user models.User
+	err = config.UsersCollection.FindOneAndUpdate(ctx, bson.M{"_id": userID}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update profile"})
+	}
+
+	return c.JSON(userProfile(&user))
+}
+
+// ChangePassword - Sets a new password after checking the current one and
+// signs out every other session
+func ChangePassword(c *fiber.Ctx) error {
+	var request struct {
+		CurrentPassword string `json:"current_password" validate:"required"`
+		NewPassword     string `json:"new_password" validate:"required"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := currentUser(ctx, c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if ok, err := confirmPassword(ctx, c, user, request.CurrentPassword, models.AuditPasswordChange, "Current password is incorrect"); !ok {
+		return err
+	}
+	if errs := utils.ValidatePassword("new_password", request.NewPassword, user.Email, user.Name); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	hashedPassword, err := utils.HashPassword(request.NewPassword)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to hash password"})
+	}
+
+	_, err = config.UsersCollection.UpdateOne(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{"password": hashedPassword, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to change password"})
+	}
+
+	// The caller stays signed in; every other device has to log in again
+	currentSession, _ := primitive.ObjectIDFromHex(c.Locals("claims").(*utils.Claims).SessionID)
+	if err := services.RevokeOtherSessions(ctx, user.ID, currentSession); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke other sessions"})
+	}
+
+	recordAudit(c, models.AuditEvent{Event: models.AuditPasswordChange, Outcome: models.AuditSuccess, TargetUserID: &user.ID})
+	return c.JSON(fiber.Map{"message": "Password changed successfully"})
+}
+
+// ChangeEmail - Starts an email change; the new address must be confirmed
+// from the link sent to it before it replaces the current one
+func ChangeEmail(c *fiber.Ctx) error {
+	var request struct {
+		Email    string `json:"email" validate:"required,email"`
+		Password string `json:"password" validate:"required"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := currentUser(ctx, c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if ok, err := confirmPassword(ctx, c, user, request.Password, models.AuditEmailChange, "Password is incorrect"); !ok {
+		return err
+	}
+	if utils.NormalizeEmail(request.Email) == user.Email {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "That is already your email address"})
+	}
+
+	switch err := services.RequestEmailChange(ctx, user, request.Email); err {
+	case nil:
+		return c.Status(http.StatusAccepted).JSON(fiber.Map{"message": "Check your new email address for a confirmation link"})
+	case services.ErrEmailTaken:
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Email already exists"})
+	default:
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start email change"})
+	}
+}
+
+// ConfirmEmailChange - Completes an email change from the link sent to the new address
+func ConfirmEmailChange(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userID, err := services.ConfirmEmailChange(ctx, c.Query("token"))
+	switch err {
+	case nil:
+		recordAudit(c, models.AuditEvent{Event: models.AuditEmailChange, Outcome: models.AuditSuccess, ActorID: &userID, TargetUserID: &userID})
+		return c.JSON(fiber.Map{"message": "Email address changed successfully"})
+	case services.ErrEmailChangeInvalid:
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired confirmation link"})
+	case services.ErrEmailTaken:
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Email already exists"})
+	default:
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to change email"})
+	}
+}
+
+// userProfile is the public view of a user returned by login and /users/me
+func userProfile(user *models.User) fiber.Map {
+	return fiber.Map{
+		"id":               user.ID.Hex(),
+		"name":             user.Name,
+		"email":            user.Email,
+		"emailVerified":    user.EmailVerified,
+		"twoFactorEnabled": user.TwoFactorEnabled,
+		"avatarUrl":        user.AvatarURL,
+		"timezone":         user.Timezone,
+		"locale":           user.Locale,
+		"pendingEmail":     user.PendingEmail,
+		"createdAt":        user.CreatedAt,
+		"updatedAt":        user.UpdatedAt,
+	}
+}
@@ -0,0 +1,17 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend/utils"
+)
+
+// validationFailed responds 422 with every failing field and rule
+func validationFailed(c *fiber.Ctx, fields []utils.FieldError) error {
+	return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+		"error":  "Validation failed",
+		"fields": fields,
+	})
+}
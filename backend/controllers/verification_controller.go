@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"backend/config"
+	"backend/services"
+	"backend/utils"
+)
+
+// VerifyEmail - Confirms the user's email address from a signed link
+func VerifyEmail(c *fiber.Ctx) error {
+	claims, err := utils.VerifyActionToken(c.Query("token"), utils.PurposeEmailVerification)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired verification link"})
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.Subject)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired verification link"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Matching on email makes links for a previous address useless
+	now := time.Now()
+	result, err := config.UsersCollection.UpdateOne(ctx,
+		bson.M{"_id": userID, "email": claims.Email},
+		bson.M{"$set": bson.M{"email_verified": true, "email_verified_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify email"})
+	}
+	if result.MatchedCount == 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired verification link"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Email verified successfully"})
+}
+
+// ResendVerification - Sends a fresh verification link. The response is the
+// same whether or not the address exists, is verified or is throttled.
+func ResendVerification(c *fiber.Ctx) error {
+	var request struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := services.ResendVerificationEmail(ctx, utils.NormalizeEmail(request.Email)); err != nil && err != mongo.ErrNoDocuments {
+		log.Println("Failed to resend verification email:", err)
+	}
+
+	return c.Status(http.StatusAccepted).JSON(fiber.Map{"message": "If the account exists and is unverified, a verification email has been sent"})
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"backend/config"
+)
+
+func TestResendVerificationNormalizesEmail(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("mixed case", func(mt *mtest.T) {
+		config.UsersCollection = mt.Client.Database("taskapp").Collection("users")
+		// No unverified account matches
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}})
+
+		app := fiber.New()
+		app.Post("/auth/verify/resend", ResendVerification)
+
+		req := httptest.NewRequest(http.MethodPost, "/auth/verify/resend", strings.NewReader(`{"email":"Jane.Doe@Example.COM"}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusAccepted {
+			mt.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusAccepted)
+		}
+
+		started := mt.GetStartedEvent()
+		if started == nil || started.CommandName != "findAndModify" {
+			mt.Fatalf("expected a findAndModify, got %+v", started)
+		}
+		if email := started.Command.Lookup("query", "email").StringValue(); email != "jane.doe@example.com" {
+			mt.Fatalf("looked up %q, want the normalised address", email)
+		}
+	})
+}
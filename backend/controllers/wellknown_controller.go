@@ -0,0 +1,13 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/utils"
+)
+
+// GetJWKS - Publishes the public signing keys so other services can verify our tokens
+func GetJWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(utils.JWKS())
+}
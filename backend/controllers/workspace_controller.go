@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"backend/config"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+)
+
+// CreateWorkspace - Creates a workspace with the caller as its admin
+func CreateWorkspace(c *fiber.Ctx) error {
+	var request struct {
+		Name string `json:"name" validate:"required,max=100"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	request.Name = strings.TrimSpace(request.Name)
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	workspace, err := services.CreateWorkspace(ctx, request.Name, userID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create workspace"})
+	}
+
+	return c.Status(http.StatusCreated).JSON(workspace)
+}
+
+// ListWorkspaces - Lists the workspaces the caller belongs to, with their role in each
+func ListWorkspaces(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var memberships []models.WorkspaceMember
+	cursor, err := config.WorkspaceMembersCollection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch workspaces"})
+	}
+	if err := cursor.All(ctx, &memberships); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch workspaces"})
+	}
+
+	roles := map[primitive.ObjectID]models.Role{}
+	ids := make([]primitive.ObjectID, 0, len(memberships))
+	for _, m := range memberships {
+		roles[m.WorkspaceID] = m.Role
+		ids = append(ids, m.WorkspaceID)
+	}
+
+	var workspaces []models.Workspace
+	cursor, err = config.WorkspacesCollection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch workspaces"})
+	}
+	if err := cursor.All(ctx, &workspaces); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch workspaces"})
+	}
+
+	response := make([]fiber.Map, 0, len(workspaces))
+	for _, w := range workspaces {
+		response = append(response, fiber.Map{"workspace": w, "role": roles[w.ID]})
+	}
+
+	return c.JSON(response)
+}
+
+// GetWorkspace - Fetches the active workspace
+func GetWorkspace(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var workspace models.Workspace
+	err := config.WorkspacesCollection.FindOne(ctx, bson.M{"_id": c.Locals("workspaceID")}).Decode(&workspace)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Workspace not found"})
+	} else if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch workspace"})
+	}
+
+	return c.JSON(fiber.Map{"workspace": workspace, "role": c.Locals("role")})
+}
+
+// UpdateWorkspace - Renames the active workspace
+func UpdateWorkspace(c *fiber.Ctx) error {
+	var request struct {
+		Name string `json:"name" validate:"required,max=100"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	request.Name = strings.TrimSpace(request.Name)
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := config.WorkspacesCollection.UpdateOne(ctx,
+		bson.M{"_id": c.Locals("workspaceID")},
+		bson.M{"$set": bson.M{"name": request.Name, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update workspace"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Workspace updated successfully"})
+}
+
+// ListWorkspaceMembers - Lists members of the active workspace
+func ListWorkspaceMembers(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var members []models.WorkspaceMember
+	cursor, err := config.WorkspaceMembersCollection.Find(ctx, bson.M{"workspace_id": c.Locals("workspaceID")})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch members"})
+	}
+	if err := cursor.All(ctx, &members); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch members"})
+	}
+
+	userIDs := make([]primitive.ObjectID, 0, len(members))
+	for _, m := range members {
+		userIDs = append(userIDs, m.UserID)
+	}
+
+	var users []models.User
+	cursor, err = config.UsersCollection.Find(ctx, bson.M{"_id": bson.M{"$in": userIDs}})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch members"})
+	}
+	if err := cursor.All(ctx, &users); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch members"})
+	}
+
+	byID := map[primitive.ObjectID]models.User{}
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	response := make([]fiber.Map, 0, len(members))
+	for _, m := range members {
+		u := byID[m.UserID]
+		response = append(response, fiber.Map{
+			"user_id":   m.UserID,
+			"name":      u.Name,
+			"email":     u.Email,
+			"role":      m.Role,
+			"joined_at": m.CreatedAt,
+		})
+	}
+
+	return c.JSON(response)
+}
+
+// UpdateWorkspaceMember - Changes a member's role in the active workspace
+func UpdateWorkspaceMember(c *fiber.Ctx) error {
+	memberID, err := primitive.ObjectIDFromHex(c.Params("userID"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	var request struct {
+		Role models.Role `json:"role" validate:"required"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if errs := utils.ValidateStruct(request); errs != nil {
+		return validationFailed(c, errs)
+	}
+	if !request.Role.IsValid() {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid role", "valid_roles": models.Roles})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	workspaceID := c.Locals("workspaceID").(primitive.ObjectID)
+	err = services.UpdateWorkspaceMemberRole(ctx, workspaceID, memberID, request.Role)
+	switch err {
+	case nil:
+		recordAudit(c, models.AuditEvent{
+			Event:        models.AuditRoleChange,
+			Outcome:      models.AuditSuccess,
+			TargetUserID: &memberID,
+			Metadata:     map[string]string{"workspace_id": workspaceID.Hex(), "to": string(request.Role)},
+		})
+		return c.JSON(fiber.Map{"message": "Member updated successfully"})
+	case services.ErrNotWorkspaceMember:
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Member not found"})
+	case services.ErrLastWorkspaceAdmin:
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "A workspace must keep at least one admin"})
+	default:
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update member"})
+	}
+}
+
+// RemoveWorkspaceMember - Removes a member from the active workspace
+func RemoveWorkspaceMember(c *fiber.Ctx) error {
+	memberID, err := primitive.ObjectIDFromHex(c.Params("userID"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	return removeMember(c, memberID)
+}
+
+// LeaveWorkspace - Removes the caller from the active workspace
+func LeaveWorkspace(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	return removeMember(c, userID)
+}
+
+func removeMember(c *fiber.Ctx, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := services.RemoveWorkspaceMember(ctx, c.Locals("workspaceID").(primitive.ObjectID), userID)
+	switch err {
+	case nil:
+		return c.JSON(fiber.Map{"message": "Member removed successfully"})
+	case services.ErrNotWorkspaceMember:
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Member not found"})
+	case services.ErrLastWorkspaceAdmin:
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "A workspace must keep at least one admin"})
+	default:
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to remove member"})
+	}
+}
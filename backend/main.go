@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/joho/godotenv"
 	"backend/config"
 	"backend/routes"
+	"backend/services"
+	"backend/utils"
 )
 
 func main() {
@@ -17,16 +21,56 @@ func main() {
 		log.Println("No .env file found, using default values")
 	}
 
-	// Initialize Fiber
-	app := fiber.New()
+	// Load JWT signing keys
+	if err := utils.LoadSigningKeys(); err != nil {
+		log.Fatal("Failed to load JWT signing keys: ", err)
+	}
+
+	// Load password policy and the breached password list
+	if err := utils.LoadPasswordPolicy(); err != nil {
+		log.Fatal("Failed to load password policy: ", err)
+	}
+
+	// Load Argon2id hashing parameters
+	if err := utils.LoadPasswordHasher(); err != nil {
+		log.Fatal("Failed to load password hashing parameters: ", err)
+	}
+
+	// Configure outgoing email
+	if err := services.SetupMailer(); err != nil {
+		log.Fatal("Failed to configure email: ", err)
+	}
+
+	// Initialize Fiber, trusting client IPs only from TRUSTED_PROXIES
+	app := fiber.New(config.ProxyConfig())
 
 	// Connect to MongoDB
 	config.ConnectDB()
 
+	// Bring data stored by earlier versions up to date
+	migrateCtx, cancelMigrate := context.WithTimeout(context.Background(), 5*time.Minute)
+	if err := services.NormalizeStoredEmails(migrateCtx); err != nil {
+		log.Fatal("Failed to normalise stored emails: ", err)
+	}
+	if err := services.MigrateToWorkspaces(migrateCtx); err != nil {
+		log.Fatal("Failed to migrate to workspaces: ", err)
+	}
+	if err := services.BootstrapAdmins(migrateCtx); err != nil {
+		log.Fatal("Failed to promote ADMIN_EMAILS: ", err)
+	}
+	cancelMigrate()
+
+	// Remove accounts whose deletion grace period has passed
+	services.StartAccountPurger(time.Hour)
+
 	// Register routes
 	routes.SetupAuthRoutes(app)
 	routes.SetupTaskRoutes(app)
+	routes.SetupWorkspaceRoutes(app)
 	routes.SetupAIRoutes(app)
+	routes.SetupWellKnownRoutes(app)
+	routes.SetupAdminRoutes(app)
+	routes.SetupUserRoutes(app)
 
 	// Start server
 	port := os.Getenv("PORT")
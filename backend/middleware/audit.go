@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"backend/models"
+	"backend/services"
+)
+
+// rejectedAuditInterval is how often the same auth.rejected failure from one
+// IP is written. Repeats in between are counted and reported with the next
+// write, so junk tokens can't flood the audit log.
+const rejectedAuditInterval = time.Minute
+
+// maxPendingAuditWrites bounds audit writes in flight; beyond it events are
+// dropped rather than letting a flood of requests pile up goroutines
+const maxPendingAuditWrites = 64
+
+var (
+	pendingAuditWrites = make(chan struct{}, maxPendingAuditWrites)
+	rejections         = &rejectionThrottle{seen: map[string]*rejectionWindow{}}
+)
+
+// auditRejected records a request the middleware turned away. userID is "" when
+// unknown; for access.denied it is the authenticated caller (the actor), for
+// auth.rejected the account the rejected credentials belong to (the target).
+// The event is written in the background so rejecting stays cheap.
+func auditRejected(c *fiber.Ctx, event, userID, reason string, metadata map[string]string) {
+	// Fiber reuses request buffers once the handler returns, so copy what
+	// the background write needs
+	ip := utils.CopyString(c.IP())
+	entry := &models.AuditEvent{
+		Event:     event,
+		Outcome:   models.AuditFailure,
+		IP:        ip,
+		UserAgent: utils.CopyString(c.Get(fiber.HeaderUserAgent)),
+		Metadata:  map[string]string{"reason": reason, "method": utils.CopyString(c.Method()), "path": utils.CopyString(c.Path())},
+	}
+	for k, v := range metadata {
+		entry.Metadata[k] = v
+	}
+	if id, err := primitive.ObjectIDFromHex(userID); err == nil {
+		if event == models.AuditAccessDenied {
+			entry.ActorID = &id
+			if c.Locals("authMethod") == AuthMethodServiceAccount {
+				entry.ActorType = models.ActorServiceAccount
+			}
+		} else {
+			entry.TargetUserID = &id
+		}
+	}
+
+	if event == models.AuditAuthRejected {
+		suppressed, record := rejections.allow(ip+"|"+reason+"|"+userID, time.Now())
+		if !record {
+			return
+		}
+		if suppressed > 0 {
+			entry.Metadata["suppressed"] = strconv.Itoa(suppressed)
+		}
+	}
+
+	select {
+	case pendingAuditWrites <- struct{}{}:
+	default:
+		log.Printf("Dropped audit event %s: too many pending writes", event)
+		return
+	}
+	go func() {
+		defer func() { <-pendingAuditWrites }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := services.RecordAuditEvent(ctx, entry); err != nil {
+			log.Printf("Failed to record audit event %s: %v", event, err)
+		}
+	}()
+}
+
+// rejectionThrottle lets one event per key through every rejectedAuditInterval
+type rejectionThrottle struct {
+	mu   sync.Mutex
+	seen map[string]*rejectionWindow
+}
+
+type rejectionWindow struct {
+	start      time.Time
+	suppressed int
+}
+
+// allow reports whether an event for key should be written now, and how many
+// were held back since the last one that was
+func (t *rejectionThrottle) allow(key string, now time.Time) (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if window, ok := t.seen[key]; ok && now.Sub(window.start) < rejectedAuditInterval {
+		window.suppressed++
+		return 0, false
+	}
+
+	// Forget finished windows now and then so the map can't grow without bound
+	if len(t.seen) >= 10000 {
+		for k, window := range t.seen {
+			if now.Sub(window.start) >= rejectedAuditInterval {
+				delete(t.seen, k)
+			}
+		}
+	}
+
+	suppressed := 0
+	if window, ok := t.seen[key]; ok {
+		suppressed = window.suppressed
+	}
+	t.seen[key] = &rejectionWindow{start: now}
+	return suppressed, true
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRejectionThrottle(t *testing.T) {
+	throttle := &rejectionThrottle{seen: map[string]*rejectionWindow{}}
+	start := time.Now()
+
+	if _, ok := throttle.allow("203.0.113.7|invalid_token|", start); !ok {
+		t.Fatal("first rejection should be recorded")
+	}
+	for i := 1; i <= 3; i++ {
+		if _, ok := throttle.allow("203.0.113.7|invalid_token|", start.Add(time.Duration(i)*time.Second)); ok {
+			t.Fatal("repeat within the interval should be held back")
+		}
+	}
+	if _, ok := throttle.allow("198.51.100.1|invalid_token|", start.Add(time.Second)); !ok {
+		t.Fatal("other clients are throttled separately")
+	}
+
+	suppressed, ok := throttle.allow("203.0.113.7|invalid_token|", start.Add(rejectedAuditInterval))
+	if !ok || suppressed != 3 {
+		t.Fatalf("after the interval got %d, %v, want 3 suppressed and recorded", suppressed, ok)
+	}
+}
@@ -1,28 +1,213 @@
 package middleware
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
+	"backend/models"
+	"backend/services"
 	"backend/utils"
 
 	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const authRealm = "task-manager"
+
+// Auth methods recorded in c.Locals("authMethod")
+const (
+	AuthMethodBearer = "bearer"
+	AuthMethodCookie = "cookie"
+	AuthMethodPAT    = "pat"
+	// AuthMethodServiceAccount requests act as a service account, not a user
+	AuthMethodServiceAccount = "service_account"
 )
 
 func AuthMiddleware(c *fiber.Ctx) error {
-	// Get token from cookies instead of headers
-	token := c.Cookies("token") // Fetch token from cookie named "token"
+	// An Authorization header takes precedence over the cookie so API clients
+	// are never silently authenticated as whoever owns the browser cookie
+	token, method, ok := extractToken(c)
+	if !ok {
+		return unauthorized(c, "invalid_request", "Authorization header must use the Bearer scheme")
+	}
 	if token == "" {
-		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Missing authentication token"})
+		return unauthorized(c, "", "Missing authentication token")
+	}
+
+	// Browsers attach the cookie to cross-site requests too, so mutations
+	// authenticated by it must prove they came from our own frontend
+	if method == AuthMethodCookie && !validCSRF(c) {
+		auditRejected(c, models.AuditAuthRejected, "", "csrf_token_invalid", nil)
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Missing or invalid CSRF token", "code": "csrf_token_invalid"})
+	}
+
+	if method == AuthMethodBearer && services.IsPersonalAccessToken(token) {
+		return authenticatePersonalAccessToken(c, token)
+	}
+	if method == AuthMethodBearer && services.IsServiceAccountKey(token) {
+		return authenticateServiceAccount(c, token)
 	}
 
 	// Verify JWT token
-	userID, err := utils.VerifyJWT(token)
+	claims, err := utils.VerifyJWT(token)
 	if err != nil {
-		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired token"})
+		// Expiry is routine, clients refresh on 401; anything else is suspicious
+		if !utils.IsExpiredTokenError(err) {
+			auditRejected(c, models.AuditAuthRejected, "", "invalid_token", nil)
+		}
+		return unauthorized(c, "invalid_token", "Invalid or expired token")
+	}
+
+	// Reject tokens revoked by logout before they expire
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	revoked, err := services.IsAccessTokenRevoked(ctx, claims)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify token"})
+	}
+	if revoked {
+		auditRejected(c, models.AuditAuthRejected, claims.UserID, "token_revoked", map[string]string{"jti": claims.ID})
+		return unauthorized(c, "invalid_token", "Token has been revoked")
+	}
+
+	// Disabled accounts lose access at once, whatever token they still hold
+	role, active, err := accountState(ctx, claims.UserID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify token"})
+	}
+	if !active {
+		auditRejected(c, models.AuditAuthRejected, claims.UserID, "account_inactive", nil)
+		return unauthorized(c, "invalid_token", "Account is disabled")
+	}
+
+	if claims.SessionID != "" {
+		services.TouchSession(claims.SessionID, c.IP())
 	}
 
 	// Store userID in locals for use in controllers
-	c.Locals("userID", userID)
+	c.Locals("userID", claims.UserID)
+	c.Locals("claims", claims)
+	c.Locals("authMethod", method)
+	c.Locals("role", role)
 
 	return c.Next()
 }
+
+// authenticatePersonalAccessToken accepts a PAT in place of a session token.
+// Its scopes are stored in locals for Require.
+func authenticatePersonalAccessToken(c *fiber.Ctx, token string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pat, err := services.AuthenticatePersonalAccessToken(ctx, token, c.IP())
+	if err == services.ErrPersonalAccessTokenInvalid {
+		auditRejected(c, models.AuditAuthRejected, "", "invalid_personal_access_token", nil)
+		return unauthorized(c, "invalid_token", "Invalid, expired or revoked token")
+	} else if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify token"})
+	}
+
+	role, active, err := accountState(ctx, pat.UserID.Hex())
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify token"})
+	}
+	if !active {
+		auditRejected(c, models.AuditAuthRejected, pat.UserID.Hex(), "account_inactive", map[string]string{"token_id": pat.ID.Hex()})
+		return unauthorized(c, "invalid_token", "Account is disabled")
+	}
+
+	c.Locals("userID", pat.UserID.Hex())
+	c.Locals("authMethod", AuthMethodPAT)
+	c.Locals("scopes", pat.Scopes)
+	c.Locals("role", role)
+
+	return c.Next()
+}
+
+// authenticateServiceAccount accepts a service account API key. The caller's
+// ID in locals is the service account's, and actorType marks it as such. No
+// role is set here: it only applies inside the account's workspace, so
+// WorkspaceContext sets it.
+func authenticateServiceAccount(c *fiber.Ctx, token string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	account, err := services.AuthenticateServiceAccountKey(ctx, token, c.IP())
+	if err == services.ErrServiceAccountKeyInvalid {
+		auditRejected(c, models.AuditAuthRejected, "", "invalid_service_account_key", nil)
+		return unauthorized(c, "invalid_token", "Invalid, expired or revoked key")
+	} else if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify token"})
+	}
+
+	c.Locals("userID", account.ID.Hex())
+	c.Locals("authMethod", AuthMethodServiceAccount)
+	c.Locals("actorType", models.ActorServiceAccount)
+	c.Locals("serviceAccount", account)
+
+	return c.Next()
+}
+
+// SessionOnly rejects personal access tokens, service account keys and
+// impersonation tokens on account management routes, so a leaked PAT can't
+// mint more tokens or change security settings, a service account can't act
+// outside its workspace and an impersonating admin can't take over the account
+func SessionOnly(c *fiber.Ctx) error {
+	if method := c.Locals("authMethod"); method == AuthMethodPAT || method == AuthMethodServiceAccount {
+		auditRejected(c, models.AuditAccessDenied, c.Locals("userID").(string), "session_required", nil)
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "This endpoint requires an interactive session", "code": "session_required"})
+	}
+	if claims, ok := c.Locals("claims").(*utils.Claims); ok && claims.Actor != nil {
+		auditRejected(c, models.AuditAccessDenied, claims.UserID, "impersonation_forbidden", map[string]string{"impersonator_id": claims.Actor.Subject})
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "This endpoint is not available while impersonating", "code": "impersonation_forbidden"})
+	}
+	return c.Next()
+}
+
+// accountState loads the caller's global role and whether the account may
+// still be used. The role is kept in locals so Require doesn't load it again.
+func accountState(ctx context.Context, userID string) (models.Role, bool, error) {
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return "", false, nil
+	}
+
+	user, err := services.GetUserAccess(ctx, id)
+	if err == mongo.ErrNoDocuments {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+
+	active := !user.Disabled && user.DeletionScheduledFor == nil
+	return user.Role, active, nil
+}
+
+// extractToken reads the token from "Authorization: Bearer <jwt>", falling back
+// to the "token" cookie only when no Authorization header was sent
+func extractToken(c *fiber.Ctx) (string, string, bool) {
+	if header := c.Get(fiber.HeaderAuthorization); header != "" {
+		scheme, token, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			return "", "", false
+		}
+		return strings.TrimSpace(token), AuthMethodBearer, true
+	}
+
+	return c.Cookies("token"), AuthMethodCookie, true
+}
+
+// unauthorized writes a 401 with an RFC 6750 WWW-Authenticate challenge
+func unauthorized(c *fiber.Ctx, errorCode, message string) error {
+	challenge := fmt.Sprintf(`Bearer realm="%s"`, authRealm)
+	if errorCode != "" {
+		challenge += fmt.Sprintf(`, error="%s", error_description="%s"`, errorCode, message)
+	}
+	c.Set(fiber.HeaderWWWAuthenticate, challenge)
+	return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": message})
+}
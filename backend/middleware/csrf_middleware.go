@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// validCSRF reports whether a request may proceed under the double-submit
+// cookie check: safe methods always may, anything else must echo the
+// csrf_token cookie in the X-CSRF-Token header
+func validCSRF(c *fiber.Ctx) bool {
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions, fiber.MethodTrace:
+		return true
+	}
+	return utils.ValidCSRFToken(c.Cookies(utils.CSRFCookieName), c.Get(utils.CSRFHeaderName))
+}
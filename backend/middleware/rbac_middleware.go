@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend/config"
+	"backend/models"
+)
+
+// Require must run after AuthMiddleware. It allows the request only when the
+// caller's role grants permission and, for personal access tokens, the token
+// carries the matching scope.
+func Require(permission models.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals("authMethod") == AuthMethodPAT && !hasScope(c, permission.Scope()) {
+			return forbidden(c, "insufficient_scope", permission)
+		}
+		// Service accounts only hold a role inside their workspace
+		if c.Locals("authMethod") == AuthMethodServiceAccount && c.Locals("workspaceID") == nil {
+			return forbidden(c, "forbidden", permission)
+		}
+
+		role, err := loadRole(c)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load permissions"})
+		}
+		if !role.Can(permission) {
+			return forbidden(c, "forbidden", permission)
+		}
+
+		return c.Next()
+	}
+}
+
+// loadRole reads the caller's role once per request. It is read from the
+// database rather than the token so role changes take effect immediately.
+func loadRole(c *fiber.Ctx) (models.Role, error) {
+	if role, ok := c.Locals("role").(models.Role); ok {
+		return role, nil
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var user models.User
+	err = config.UsersCollection.FindOne(ctx, bson.M{"_id": userID}, options.FindOne().SetProjection(bson.M{"role": 1})).Decode(&user)
+	if err != nil {
+		return "", err
+	}
+
+	c.Locals("role", user.Role)
+	return user.Role, nil
+}
+
+func hasScope(c *fiber.Ctx, scope string) bool {
+	if scope == "" {
+		return false
+	}
+	scopes, _ := c.Locals("scopes").([]string)
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// forbidden is the single 403 body used for every authorization failure
+func forbidden(c *fiber.Ctx, code string, permission models.Permission) error {
+	userID, _ := c.Locals("userID").(string)
+	auditRejected(c, models.AuditAccessDenied, userID, code, map[string]string{"permission": string(permission)})
+
+	return c.Status(http.StatusForbidden).JSON(fiber.Map{
+		"error":      "You do not have permission to perform this action",
+		"code":       code,
+		"permission": permission,
+	})
+}
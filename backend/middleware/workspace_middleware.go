@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"backend/models"
+	"backend/services"
+)
+
+// WorkspaceHeader selects the active workspace on routes without a :workspaceID path parameter
+const WorkspaceHeader = "X-Workspace-ID"
+
+// WorkspaceContext must run after AuthMiddleware. It resolves the active
+// workspace from the :workspaceID path parameter or the X-Workspace-ID
+// header, checks membership and stores the workspace role in locals, so
+// Require evaluates permissions against the caller's role in that workspace.
+func WorkspaceContext(c *fiber.Ctx) error {
+	rawID := c.Params("workspaceID")
+	if rawID == "" {
+		rawID = c.Get(WorkspaceHeader)
+	}
+
+	// A service account always works in its own workspace, with its own role
+	if account, ok := c.Locals("serviceAccount").(*models.ServiceAccount); ok {
+		if rawID != "" && rawID != account.WorkspaceID.Hex() {
+			auditRejected(c, models.AuditAccessDenied, account.ID.Hex(), "not_workspace_member", map[string]string{"workspace_id": rawID})
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Workspace not found"})
+		}
+		c.Locals("workspaceID", account.WorkspaceID)
+		c.Locals("role", account.Role)
+		return c.Next()
+	}
+
+	if rawID == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Workspace not specified, send the " + WorkspaceHeader + " header"})
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(rawID)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Non-members get 404 so workspace IDs can't be probed
+	member, err := services.GetWorkspaceMembership(ctx, workspaceID, userID)
+	if err == services.ErrNotWorkspaceMember {
+		auditRejected(c, models.AuditAccessDenied, userID.Hex(), "not_workspace_member", map[string]string{"workspace_id": workspaceID.Hex()})
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Workspace not found"})
+	} else if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load workspace"})
+	}
+
+	c.Locals("workspaceID", workspaceID)
+	c.Locals("role", member.Role)
+
+	return c.Next()
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Audit event types
+const (
+	AuditLogin                = "login"
+	AuditLoginLockout         = "login.lockout"
+	AuditLogout               = "logout"
+	AuditRegister             = "register"
+	AuditPasswordChange       = "password.change"
+	AuditPasswordReset        = "password.reset"
+	AuditEmailChange          = "email.change"
+	AuditTwoFactorEnable      = "two_factor.enable"
+	AuditTwoFactorDisable     = "two_factor.disable"
+	AuditTokenCreate          = "token.create"
+	AuditTokenRevoke          = "token.revoke"
+	AuditSessionRevoke        = "session.revoke"
+	AuditRoleChange           = "role.change"
+	AuditAuthRejected         = "auth.rejected"
+	AuditAccessDenied         = "access.denied"
+	AuditUserDisable          = "user.disable"
+	AuditUserEnable           = "user.enable"
+	AuditUserLogout           = "user.logout"
+	AuditUserTwoFactorReset   = "user.two_factor_reset"
+	AuditUserUnlock           = "user.unlock"
+	AuditUserImpersonate      = "user.impersonate"
+	AuditServiceAccountCreate = "service_account.create"
+	AuditServiceAccountUpdate = "service_account.update"
+	AuditServiceAccountDelete = "service_account.delete"
+)
+
+// Audit event outcomes
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditEvent is an append-only record of a security-relevant action.
+// ActorID is who performed it, if known; ImpersonatorID is set when an admin
+// acted through an impersonation token. TargetUserID is the account affected.
+type AuditEvent struct {
+	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Event          string              `bson:"event" json:"event"`
+	Outcome        string              `bson:"outcome" json:"outcome"`
+	ActorID        *primitive.ObjectID `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	ActorType      ActorType           `bson:"actor_type,omitempty" json:"actor_type,omitempty"` // Unset for users
+	ImpersonatorID *primitive.ObjectID `bson:"impersonator_id,omitempty" json:"impersonator_id,omitempty"`
+	TargetUserID   *primitive.ObjectID `bson:"target_user_id,omitempty" json:"target_user_id,omitempty"`
+	IP             string              `bson:"ip,omitempty" json:"ip,omitempty"`
+	UserAgent      string              `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	Metadata       map[string]string   `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	CreatedAt      time.Time           `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LoginAttempt counts consecutive failed logins for one key, either an
+// account ("email:<address>") or a client ("ip:<address>"). The document
+// expires after a quiet period, which resets the counter.
+type LoginAttempt struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Key           string             `bson:"key" json:"key"`
+	Failures      int                `bson:"failures" json:"failures"`
+	LockedUntil   *time.Time         `bson:"locked_until,omitempty" json:"locked_until,omitempty"`
+	LastFailureAt time.Time          `bson:"last_failure_at" json:"last_failure_at"`
+	ExpiresAt     time.Time          `bson:"expires_at" json:"expires_at"`
+}
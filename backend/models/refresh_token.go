@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshToken is a single link in a rotating refresh token chain. Only the
+// SHA-256 hash of the token is stored; every rotation within one login shares
+// the same FamilyID so a replayed token can revoke the whole chain.
+type RefreshToken struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	FamilyID   primitive.ObjectID  `bson:"family_id" json:"family_id"`
+	TokenHash  string              `bson:"token_hash" json:"-"`
+	ReplacedBy *primitive.ObjectID `bson:"replaced_by,omitempty" json:"replaced_by,omitempty"`
+	RevokedAt  *time.Time          `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	ExpiresAt  time.Time           `bson:"expires_at" json:"expires_at"`
+	CreatedAt  time.Time           `bson:"created_at" json:"created_at"`
+}
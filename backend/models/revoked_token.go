@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RevokedToken is a denylist entry for access tokens. An entry either names a
+// single token by JTI, revokes every token issued in one session, or revokes
+// every token issued to UserID before IssuedBefore. Entries expire once the
+// tokens they cover would have expired.
+type RevokedToken struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	JTI          string             `bson:"jti,omitempty" json:"jti,omitempty"`
+	SessionID    string             `bson:"session_id,omitempty" json:"session_id,omitempty"`
+	UserID       primitive.ObjectID `bson:"user_id" json:"user_id"`
+	IssuedBefore *time.Time         `bson:"issued_before,omitempty" json:"issued_before,omitempty"`
+	ExpiresAt    time.Time          `bson:"expires_at" json:"expires_at"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,88 @@
+package models
+
+// Role controls what a user may do. User.Role applies application-wide (for
+// example user management); WorkspaceMember.Role applies inside a workspace.
+type Role string
+
+const (
+	RoleAdmin   Role = "admin"
+	RoleManager Role = "manager"
+	RoleMember  Role = "member"
+	RoleViewer  Role = "viewer"
+)
+
+// Roles lists every assignable role
+var Roles = []Role{RoleAdmin, RoleManager, RoleMember, RoleViewer}
+
+// Permission names a single action checked by middleware.Require
+type Permission string
+
+const (
+	PermTaskRead         Permission = "tasks:read"
+	PermTaskReadAll      Permission = "tasks:read_all"
+	PermTaskCreate       Permission = "tasks:create"
+	PermTaskUpdate       Permission = "tasks:update"
+	PermTaskUpdateStatus Permission = "tasks:update_status"
+	PermTaskDelete       Permission = "tasks:delete"
+	// PermTaskManageAny lifts the creator/assignee restriction on single tasks
+	PermTaskManageAny   Permission = "tasks:manage_any"
+	PermWorkspaceView   Permission = "workspace:view"
+	PermWorkspaceManage Permission = "workspace:manage"
+	PermUsersManage     Permission = "users:manage"
+	PermAuditRead       Permission = "audit:read"
+)
+
+// rolePermissions is the permission matrix
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermTaskRead, PermTaskReadAll, PermTaskCreate, PermTaskUpdate, PermTaskUpdateStatus, PermTaskDelete,
+		PermTaskManageAny, PermWorkspaceView, PermWorkspaceManage, PermUsersManage, PermAuditRead,
+	},
+	RoleManager: {
+		PermTaskRead, PermTaskReadAll, PermTaskCreate, PermTaskUpdate, PermTaskUpdateStatus, PermTaskDelete,
+		PermTaskManageAny, PermWorkspaceView,
+	},
+	// Members act only on tasks they created or are assigned to; see PermTaskManageAny
+	RoleMember: {
+		PermTaskRead, PermTaskCreate, PermTaskUpdate, PermTaskUpdateStatus, PermTaskDelete, PermWorkspaceView,
+	},
+	RoleViewer: {
+		PermTaskRead, PermWorkspaceView,
+	},
+}
+
+// permissionScopes maps permissions to the personal access token scope that
+// grants them. Permissions missing here can't be exercised with a PAT.
+var permissionScopes = map[Permission]string{
+	PermTaskRead:         ScopeTasksRead,
+	PermTaskReadAll:      ScopeTasksRead,
+	PermTaskCreate:       ScopeTasksWrite,
+	PermTaskUpdate:       ScopeTasksWrite,
+	PermTaskUpdateStatus: ScopeTasksWrite,
+	PermTaskDelete:       ScopeTasksWrite,
+}
+
+// IsValid reports whether r is one of the defined roles
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can reports whether the role grants permission p. Accounts created before
+// roles existed have no role stored and are treated as members.
+func (r Role) Can(p Permission) bool {
+	if r == "" {
+		r = RoleMember
+	}
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Scope returns the PAT scope covering p, or "" if PATs may not use it
+func (p Permission) Scope() string {
+	return permissionScopes[p]
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ActorType says whether an action recorded on a task was taken by a person
+// or by a service account. Records from before service accounts existed have
+// no type and were made by users.
+type ActorType string
+
+const (
+	ActorUser           ActorType = "user"
+	ActorServiceAccount ActorType = "service_account"
+)
+
+// ServiceAccount is a non-interactive identity for bots and integrations. It
+// belongs to a single workspace, acts there with Role and authenticates only
+// with API keys; it has no email or password, so it can never log in.
+type ServiceAccount struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID `bson:"workspace_id" json:"workspace_id"`
+	Name        string             `bson:"name" json:"name"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	Role        Role               `bson:"role" json:"role"`
+	Disabled    bool               `bson:"disabled,omitempty" json:"disabled"`
+	CreatedBy   primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// ServiceAccountKey is an API key for a service account. Like a personal
+// access token, the secret is shown once at creation; only its SHA-256 hash
+// and a short display prefix are stored.
+type ServiceAccountKey struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ServiceAccountID primitive.ObjectID `bson:"service_account_id" json:"service_account_id"`
+	Name             string             `bson:"name" json:"name"`
+	Prefix           string             `bson:"prefix" json:"prefix"`
+	TokenHash        string             `bson:"token_hash" json:"-"`
+	ExpiresAt        time.Time          `bson:"expires_at" json:"expires_at"`
+	LastUsedAt       *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	LastUsedIP       string             `bson:"last_used_ip,omitempty" json:"last_used_ip,omitempty"`
+	RevokedAt        *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
+}
@@ -23,21 +23,29 @@ const (
 	Urgent PriorityLevel = "urgent"
 )
 
+// Comment on a task. UserID is the author, a user or a service account as
+// AuthorType says.
 type Comment struct {
-	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
-	Text      string             `bson:"text" json:"text"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	AuthorType ActorType          `bson:"author_type,omitempty" json:"author_type,omitempty"`
+	Text       string             `bson:"text" json:"text" validate:"required,max=5000"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
 }
 
 type Task struct {
-	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
-	Title       string               `bson:"title" json:"title" validate:"required,min=3,max=100"`
-	Description string               `bson:"description,omitempty" json:"description,omitempty"`
-	AssignedTo  []primitive.ObjectID `bson:"assigned_to,omitempty" json:"assigned_to,omitempty"` // Multiple users
-	Status      TaskStatus           `bson:"status" json:"status" validate:"required,oneof=pending in_progress completed"`
-	Priority    PriorityLevel        `bson:"priority" json:"priority" validate:"oneof=low medium high urgent"`
-	DueDate     *time.Time           `bson:"due_date,omitempty" json:"due_date,omitempty"`
-	Comments    []Comment            `bson:"comments,omitempty" json:"comments,omitempty"` // Task discussion
-	CreatedAt   time.Time            `bson:"created_at,omitempty" json:"created_at"`
-	UpdatedAt   time.Time            `bson:"updated_at,omitempty" json:"updated_at"`
+	ID            primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Title         string               `bson:"title" json:"title" validate:"required,min=3,max=100"`
+	Description   string               `bson:"description,omitempty" json:"description,omitempty"`
+	WorkspaceID   primitive.ObjectID   `bson:"workspace_id,omitempty" json:"workspace_id"`
+	CreatedBy     primitive.ObjectID   `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedByType ActorType            `bson:"created_by_type,omitempty" json:"created_by_type,omitempty"`
+	UpdatedBy     primitive.ObjectID   `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+	UpdatedByType ActorType            `bson:"updated_by_type,omitempty" json:"updated_by_type,omitempty"`
+	AssignedTo    []primitive.ObjectID `bson:"assigned_to,omitempty" json:"assigned_to,omitempty"` // Multiple users
+	Status        TaskStatus           `bson:"status" json:"status" validate:"required,oneof=pending in_progress completed"`
+	Priority      PriorityLevel        `bson:"priority" json:"priority" validate:"oneof=low medium high urgent"`
+	DueDate       *time.Time           `bson:"due_date,omitempty" json:"due_date,omitempty"`
+	Comments      []Comment            `bson:"comments,omitempty" json:"comments,omitempty"` // Task discussion
+	CreatedAt     time.Time            `bson:"created_at,omitempty" json:"created_at"`
+	UpdatedAt     time.Time            `bson:"updated_at,omitempty" json:"updated_at"`
 }
@@ -10,7 +10,39 @@ type User struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Name      string             `bson:"name" json:"name" validate:"required,min=3,max=50"`
 	Email     string             `bson:"email" json:"email" validate:"required,email"`
-	Password  string             `bson:"password" json:"password"` // Allow JSON parsing
+	Password  string             `bson:"password" json:"password" validate:"required"` // Allow JSON parsing
+	Role      Role               `bson:"role,omitempty" json:"role"`
 	CreatedAt time.Time          `bson:"created_at,omitempty" json:"created_at"`
 	UpdatedAt time.Time          `bson:"updated_at,omitempty" json:"updated_at"`
+
+	// Profile preferences
+	AvatarURL string `bson:"avatar_url,omitempty" json:"avatar_url,omitempty"`
+	Timezone  string `bson:"timezone,omitempty" json:"timezone,omitempty"`
+	Locale    string `bson:"locale,omitempty" json:"locale,omitempty"`
+
+	// Email verification
+	EmailVerified      bool       `bson:"email_verified" json:"email_verified"`
+	EmailVerifiedAt    *time.Time `bson:"email_verified_at,omitempty" json:"email_verified_at,omitempty"`
+	VerificationSentAt *time.Time `bson:"verification_sent_at,omitempty" json:"-"`
+	PendingEmail       string     `bson:"pending_email,omitempty" json:"-"` // Awaiting confirmation from the new address
+	MagicLinkSentAt    *time.Time `bson:"magic_link_sent_at,omitempty" json:"-"`
+
+	// TOTP two-factor authentication. Recovery codes are stored hashed.
+	TwoFactorEnabled  bool     `bson:"two_factor_enabled" json:"two_factor_enabled"`
+	TOTPSecret        string   `bson:"totp_secret,omitempty" json:"-"`
+	TOTPPendingSecret string   `bson:"totp_pending_secret,omitempty" json:"-"`
+	TOTPLastStep      int64    `bson:"totp_last_step,omitempty" json:"-"`
+	RecoveryCodes     []string `bson:"recovery_codes,omitempty" json:"-"`
+
+	// Single sign-on identity linked to this account
+	OIDCIssuer  string `bson:"oidc_issuer,omitempty" json:"-"`
+	OIDCSubject string `bson:"oidc_subject,omitempty" json:"-"`
+
+	// Accounts disabled by an admin can't sign in or use existing tokens
+	Disabled   bool       `bson:"disabled,omitempty" json:"disabled,omitempty"`
+	DisabledAt *time.Time `bson:"disabled_at,omitempty" json:"-"`
+
+	// Account deletion. The account is purged once DeletionScheduledFor passes.
+	DeletionRequestedAt  *time.Time `bson:"deletion_requested_at,omitempty" json:"-"`
+	DeletionScheduledFor *time.Time `bson:"deletion_scheduled_for,omitempty" json:"-"`
 }
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Workspace is a tenant. Every task belongs to exactly one workspace.
+type Workspace struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name" validate:"required,min=1,max=100"`
+	CreatedBy primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time          `bson:"created_at,omitempty" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at,omitempty" json:"updated_at"`
+}
+
+// WorkspaceMember gives a user a role inside one workspace. The role here,
+// not User.Role, decides what the user may do with that workspace's tasks.
+type WorkspaceMember struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID `bson:"workspace_id" json:"workspace_id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Role        Role               `bson:"role" json:"role"`
+	CreatedAt   time.Time          `bson:"created_at,omitempty" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at,omitempty" json:"updated_at"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WorkspaceInvitation is a pending offer to join a workspace with a role. The
+// emailed link is a signed token naming the invitation, so revoking or
+// accepting the invitation here invalidates the link.
+type WorkspaceInvitation struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID  `bson:"workspace_id" json:"workspace_id"`
+	Email       string              `bson:"email" json:"email"`
+	Role        Role                `bson:"role" json:"role"`
+	InvitedBy   primitive.ObjectID  `bson:"invited_by" json:"invited_by"`
+	AcceptedAt  *time.Time          `bson:"accepted_at,omitempty" json:"accepted_at,omitempty"`
+	AcceptedBy  *primitive.ObjectID `bson:"accepted_by,omitempty" json:"accepted_by,omitempty"`
+	RevokedAt   *time.Time          `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	ExpiresAt   time.Time           `bson:"expires_at" json:"expires_at"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"backend/controllers"
+	"backend/middleware"
+	"backend/models"
+)
+
+func SetupAdminRoutes(app *fiber.App) {
+	admin := app.Group("/admin", middleware.AuthMiddleware, middleware.SessionOnly, middleware.Require(models.PermUsersManage))
+
+	admin.Get("/users", controllers.ListUsers)                         // Search and filter users
+	admin.Get("/users/:id", controllers.GetUser)                       // Get one user's account details
+	admin.Post("/users/:id/disable", controllers.DisableUser)          // Block sign-in and revoke sessions
+	admin.Post("/users/:id/enable", controllers.EnableUser)            // Allow sign-in again
+	admin.Post("/users/:id/logout", controllers.ForceLogoutUser)       // Revoke every session
+	admin.Post("/users/:id/2fa/reset", controllers.ResetUserTwoFactor) // Turn off 2FA for a lost authenticator
+	admin.Patch("/users/:id/role", controllers.UpdateUserRole)         // Change the global role
+	admin.Post("/users/:id/impersonate", controllers.ImpersonateUser)  // Issue an audited impersonation token
+	admin.Post("/users/:id/unlock", controllers.UnlockUser)            // Lift a login lockout
+
+	// Security audit log, filterable by user_id, event, from and to
+	admin.Get("/audit-events", middleware.Require(models.PermAuditRead), controllers.ListAuditEvents)
+}
@@ -1,12 +1,53 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"backend/config"
 	"backend/controllers"
+	"backend/middleware"
 )
 
 func SetupAuthRoutes(app *fiber.App) {
 	auth := app.Group("/auth")
 	auth.Post("/register", controllers.Register)
 	auth.Post("/login", controllers.Login)
+	auth.Post("/refresh", controllers.Refresh)
+	auth.Get("/csrf", controllers.CSRFToken)
+	auth.Get("/verify", controllers.VerifyEmail)
+	auth.Post("/verify/resend", controllers.ResendVerification)
+	auth.Get("/email/confirm", controllers.ConfirmEmailChange)
+	auth.Get("/account/restore", controllers.RestoreAccount)
+	auth.Post("/forgot-password", controllers.ForgotPassword)
+	auth.Post("/reset-password", controllers.ResetPassword)
+
+	// OpenID Connect single sign-on
+	auth.Get("/oidc/login", controllers.StartOIDCLogin)
+	auth.Get("/oidc/callback", controllers.OIDCCallback)
+
+	// Passwordless login by emailed link
+	auth.Post("/magic-link", magicLinkLimiter(), controllers.RequestMagicLink)
+	auth.Post("/magic-link/verify", magicLinkLimiter(), controllers.ConsumeMagicLink)
+
+	// Two-factor authentication
+	auth.Post("/2fa/verify", controllers.VerifyTwoFactorLogin)
+	auth.Post("/2fa/enroll", middleware.AuthMiddleware, middleware.SessionOnly, controllers.EnrollTwoFactor)
+	auth.Post("/2fa/confirm", middleware.AuthMiddleware, middleware.SessionOnly, controllers.ConfirmTwoFactor)
+	auth.Post("/2fa/disable", middleware.AuthMiddleware, middleware.SessionOnly, controllers.DisableTwoFactor)
+	auth.Post("/logout", middleware.AuthMiddleware, middleware.SessionOnly, controllers.Logout)
+	auth.Post("/logout/all", middleware.AuthMiddleware, middleware.SessionOnly, controllers.LogoutAll)
+}
+
+// magicLinkLimiter caps magic link requests per client IP. Each address is
+// also throttled in the database, see services.SendMagicLink.
+func magicLinkLimiter() fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:        config.GetEnvInt("MAGIC_LINK_IP_LIMIT", 5),
+		Expiration: 15 * time.Minute,
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Too many requests, please try again later"})
+		},
+	})
 }
@@ -4,16 +4,23 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"backend/controllers"
 	"backend/middleware"
+	"backend/models"
 )
 
 func SetupTaskRoutes(app *fiber.App) {
-	task := app.Group("/tasks", middleware.AuthMiddleware)
+	// The active workspace comes from the X-Workspace-ID header here; the same
+	// routes are mounted under /workspaces/:workspaceID/tasks by SetupWorkspaceRoutes
+	registerTaskRoutes(app.Group("/tasks", middleware.AuthMiddleware, middleware.WorkspaceContext))
+}
 
-	task.Post("/", controllers.CreateTask)          // Create a new task
-	task.Get("/", controllers.GetAllTasks)         // Get all tasks
-	task.Get("/assigned", controllers.GetMyTasks)  // Get tasks assigned to the logged-in user
-	task.Get("/:id", controllers.GetTaskByID)      // Get task by ID
-	task.Put("/:id", controllers.UpdateTask)       // Update task details
-	task.Patch("/:id/status", controllers.UpdateTaskStatus) // Update task status
-	task.Delete("/:id", controllers.DeleteTask)    // Delete task
+func registerTaskRoutes(task fiber.Router) {
+	// Each route declares the permission it needs; see models.Role for the matrix
+	task.Post("/", middleware.Require(models.PermTaskCreate), controllers.CreateTask)                        // Create a new task
+	task.Get("/", middleware.Require(models.PermTaskRead), controllers.GetAllTasks)                          // Get the tasks visible to the caller
+	task.Get("/assigned", middleware.Require(models.PermTaskRead), controllers.GetMyTasks)                   // Get tasks assigned to the logged-in user
+	task.Get("/:id", middleware.Require(models.PermTaskRead), controllers.GetTaskByID)                       // Get task by ID
+	task.Put("/:id", middleware.Require(models.PermTaskUpdate), controllers.UpdateTask)                      // Update task details
+	task.Patch("/:id/status", middleware.Require(models.PermTaskUpdateStatus), controllers.UpdateTaskStatus) // Update task status
+	task.Post("/:id/comments", middleware.Require(models.PermTaskUpdate), controllers.AddTaskComment)        // Comment on a task
+	task.Delete("/:id", middleware.Require(models.PermTaskDelete), controllers.DeleteTask)                   // Delete task
 }
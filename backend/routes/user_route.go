@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"backend/controllers"
+	"backend/middleware"
+)
+
+func SetupUserRoutes(app *fiber.App) {
+	users := app.Group("/users", middleware.AuthMiddleware)
+
+	users.Get("/me", controllers.GetMe)
+	users.Patch("/me", middleware.SessionOnly, controllers.UpdateMe)
+	users.Post("/me/password", middleware.SessionOnly, controllers.ChangePassword)
+	users.Post("/me/email", middleware.SessionOnly, controllers.ChangeEmail)
+	users.Get("/me/export", middleware.SessionOnly, controllers.ExportMyData)
+	users.Delete("/me", middleware.SessionOnly, controllers.DeleteMe)
+
+	// Personal access tokens can't be managed with a personal access token
+	users.Post("/me/tokens", middleware.SessionOnly, controllers.CreatePersonalAccessToken)
+	users.Get("/me/tokens", middleware.SessionOnly, controllers.ListPersonalAccessTokens)
+	users.Delete("/me/tokens/:id", middleware.SessionOnly, controllers.RevokePersonalAccessToken)
+
+	// Devices the caller is logged in on
+	users.Get("/me/sessions", middleware.SessionOnly, controllers.ListSessions)
+	users.Delete("/me/sessions/:id", middleware.SessionOnly, controllers.RevokeSession)
+}
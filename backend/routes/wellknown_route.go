@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"backend/controllers"
+)
+
+func SetupWellKnownRoutes(app *fiber.App) {
+	wellKnown := app.Group("/.well-known")
+	wellKnown.Get("/jwks.json", controllers.GetJWKS)
+}
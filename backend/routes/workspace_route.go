@@ -0,0 +1,46 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"backend/controllers"
+	"backend/middleware"
+	"backend/models"
+)
+
+func SetupWorkspaceRoutes(app *fiber.App) {
+	workspaces := app.Group("/workspaces", middleware.AuthMiddleware)
+
+	workspaces.Post("/", middleware.SessionOnly, controllers.CreateWorkspace) // Create a workspace
+	workspaces.Get("/", middleware.SessionOnly, controllers.ListWorkspaces)   // List my workspaces
+
+	// Routes below act on the workspace named in the path
+	workspace := middleware.WorkspaceContext
+	view := middleware.Require(models.PermWorkspaceView)
+	manage := middleware.Require(models.PermWorkspaceManage)
+
+	workspaces.Get("/:workspaceID", workspace, view, controllers.GetWorkspace)
+	workspaces.Patch("/:workspaceID", workspace, manage, controllers.UpdateWorkspace)
+	workspaces.Get("/:workspaceID/members", workspace, view, controllers.ListWorkspaceMembers)
+	workspaces.Delete("/:workspaceID/members/me", workspace, middleware.SessionOnly, controllers.LeaveWorkspace)
+	workspaces.Patch("/:workspaceID/members/:userID", workspace, manage, controllers.UpdateWorkspaceMember)
+	workspaces.Delete("/:workspaceID/members/:userID", workspace, manage, controllers.RemoveWorkspaceMember)
+	workspaces.Post("/:workspaceID/invitations", workspace, manage, controllers.CreateInvitation)
+	workspaces.Get("/:workspaceID/invitations", workspace, manage, controllers.ListInvitations)
+	workspaces.Delete("/:workspaceID/invitations/:invitationID", workspace, manage, controllers.RevokeInvitation)
+	workspaces.Post("/:workspaceID/service-accounts", workspace, manage, controllers.CreateServiceAccount)
+	workspaces.Get("/:workspaceID/service-accounts", workspace, manage, controllers.ListServiceAccounts)
+	workspaces.Patch("/:workspaceID/service-accounts/:serviceAccountID", workspace, manage, controllers.UpdateServiceAccount)
+	workspaces.Delete("/:workspaceID/service-accounts/:serviceAccountID", workspace, manage, controllers.DeleteServiceAccount)
+	workspaces.Post("/:workspaceID/service-accounts/:serviceAccountID/keys", workspace, manage, controllers.CreateServiceAccountKey)
+	workspaces.Get("/:workspaceID/service-accounts/:serviceAccountID/keys", workspace, manage, controllers.ListServiceAccountKeys)
+	workspaces.Delete("/:workspaceID/service-accounts/:serviceAccountID/keys/:keyID", workspace, manage, controllers.RevokeServiceAccountKey)
+
+	// Members only join through invitations, so nobody is added without consent
+	// and the API never reveals whether an email has an account. Invitees who
+	// already have an account accept while logged in; new users pass the token
+	// to /auth/register instead
+	app.Post("/invitations/accept", middleware.AuthMiddleware, middleware.SessionOnly, controllers.AcceptInvitation)
+
+	// Task routes scoped by path instead of the X-Workspace-ID header
+	registerTaskRoutes(workspaces.Group("/:workspaceID/tasks", middleware.WorkspaceContext))
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+)
+
+var ErrAccountRestoreInvalid = errors.New("invalid or expired account restore link")
+
+// AccountDeletionGrace - How long a deleted account can still be restored
+func AccountDeletionGrace() time.Duration {
+	return config.GetEnvDuration("ACCOUNT_DELETION_GRACE", 30*24*time.Hour)
+}
+
+// RequestAccountDeletion - Schedules the account for purging after the grace
+// period, signs it out everywhere and emails a link to undo the request
+func RequestAccountDeletion(ctx context.Context, user *models.User) (time.Time, error) {
+	now := time.Now()
+	grace := AccountDeletionGrace()
+	scheduledFor := now.Add(grace)
+
+	_, err := config.UsersCollection.UpdateOne(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{
+			"deletion_requested_at":  now,
+			"deletion_scheduled_for": scheduledFor,
+			"updated_at":             now,
+		}},
+	)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if err := RevokeAllUserTokens(ctx, user.ID); err != nil {
+		return time.Time{}, err
+	}
+	if err := RevokeUserPersonalAccessTokens(ctx, user.ID); err != nil {
+		return time.Time{}, err
+	}
+
+	token, err := utils.GenerateActionToken(utils.PurposeAccountRestore, user.ID.Hex(), user.Email, grace)
+	if err != nil {
+		return scheduledFor, err
+	}
+
+	link := fmt.Sprintf("%s/auth/account/restore?token=%s", config.AppBaseURL(), url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\nYour account is scheduled for deletion on %s. Until then you can restore it by opening the link below:\n\n%s\n\nAfter that date your data is removed permanently.\n",
+		user.Name, scheduledFor.Format(time.RFC1123), link)
+	if err := SendMail(user.Email, "Your account is scheduled for deletion", body); err != nil {
+		log.Println("Failed to send account deletion notice:", err)
+	}
+
+	return scheduledFor, nil
+}
+
+// RestoreAccount - Cancels a pending deletion from the emailed restore link
+func RestoreAccount(ctx context.Context, token string) error {
+	claims, err := utils.VerifyActionToken(token, utils.PurposeAccountRestore)
+	if err != nil {
+		return ErrAccountRestoreInvalid
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.Subject)
+	if err != nil {
+		return ErrAccountRestoreInvalid
+	}
+
+	result, err := config.UsersCollection.UpdateOne(ctx,
+		bson.M{"_id": userID, "deletion_scheduled_for": bson.M{"$gt": time.Now()}},
+		bson.M{
+			"$unset": bson.M{"deletion_requested_at": "", "deletion_scheduled_for": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrAccountRestoreInvalid
+	}
+	return nil
+}
+
+// StartAccountPurger - Purges accounts whose grace period has passed, once at
+// startup and then every interval, for the life of the process
+func StartAccountPurger(interval time.Duration) {
+	go func() {
+		for {
+			if err := PurgeDueAccounts(context.Background()); err != nil {
+				log.Println("Account purge failed:", err)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// PurgeDueAccounts - Permanently removes every account past its deletion date
+func PurgeDueAccounts(ctx context.Context) error {
+	cursor, err := config.UsersCollection.Find(ctx,
+		bson.M{"deletion_scheduled_for": bson.M{"$lte": time.Now()}},
+		options.Find().SetProjection(bson.M{"_id": 1, "email": 1}),
+	)
+	if err != nil {
+		return err
+	}
+
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		purgeCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		err := purgeUser(purgeCtx, &user)
+		cancel()
+		if err != nil {
+			log.Printf("Failed to purge account %s: %v", user.ID.Hex(), err)
+			continue
+		}
+		log.Printf("Purged account %s", user.ID.Hex())
+	}
+	return nil
+}
+
+// purgeUser anonymizes the user's contributions to shared workspaces and
+// deletes everything else tied to the account. Each step is idempotent so a
+// purge interrupted halfway is finished by the next run.
+func purgeUser(ctx context.Context, user *models.User) error {
+	uid := user.ID
+
+	// Comments stay in the task history without an author
+	_, err := config.TasksCollection.UpdateMany(ctx,
+		bson.M{"comments.user_id": uid},
+		bson.M{"$set": bson.M{"comments.$[c].user_id": primitive.NilObjectID}},
+		options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"c.user_id": uid}}}),
+	)
+	if err != nil {
+		return err
+	}
+
+	if _, err := config.TasksCollection.UpdateMany(ctx, bson.M{"assigned_to": uid}, bson.M{"$pull": bson.M{"assigned_to": uid}}); err != nil {
+		return err
+	}
+	if _, err := config.TasksCollection.UpdateMany(ctx, bson.M{"created_by": uid}, bson.M{"$unset": bson.M{"created_by": ""}}); err != nil {
+		return err
+	}
+
+	if err := leaveAllWorkspaces(ctx, uid); err != nil {
+		return err
+	}
+
+	byUser := bson.M{"user_id": uid}
+	for _, collection := range []*mongo.Collection{
+		config.SessionsCollection,
+		config.RefreshTokensCollection,
+		config.PersonalAccessTokensCollection,
+		config.PasswordResetsCollection,
+		config.MagicLinksCollection,
+	} {
+		if _, err := collection.DeleteMany(ctx, byUser); err != nil {
+			return err
+		}
+	}
+	if _, err := config.LoginAttemptsCollection.DeleteOne(ctx, bson.M{"key": accountLockKey(user.Email)}); err != nil {
+		return err
+	}
+
+	_, err = config.UsersCollection.DeleteOne(ctx, bson.M{"_id": uid})
+	return err
+}
+
+// leaveAllWorkspaces removes the user's memberships. A workspace left empty is
+// deleted with its tasks; one left without an admin promotes its longest-standing member.
+func leaveAllWorkspaces(ctx context.Context, userID primitive.ObjectID) error {
+	var memberships []models.WorkspaceMember
+	cursor, err := config.WorkspaceMembersCollection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return err
+	}
+	if err := cursor.All(ctx, &memberships); err != nil {
+		return err
+	}
+
+	for _, m := range memberships {
+		var next models.WorkspaceMember
+		err := config.WorkspaceMembersCollection.FindOne(ctx,
+			bson.M{"workspace_id": m.WorkspaceID, "user_id": bson.M{"$ne": userID}},
+			options.FindOne().SetSort(bson.M{"created_at": 1}),
+		).Decode(&next)
+		if err == mongo.ErrNoDocuments {
+			if err := deleteWorkspace(ctx, m.WorkspaceID); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		// Promote before leaving so the workspace is never without an admin
+		if m.Role == models.RoleAdmin {
+			if err := ensureAnotherAdmin(ctx, m.WorkspaceID, userID); err == ErrLastWorkspaceAdmin {
+				if err := UpdateWorkspaceMemberRole(ctx, m.WorkspaceID, next.UserID, models.RoleAdmin); err != nil {
+					return err
+				}
+			} else if err != nil {
+				return err
+			}
+		}
+
+		if _, err := config.WorkspaceMembersCollection.DeleteOne(ctx, bson.M{"_id": m.ID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	byWorkspace := bson.M{"workspace_id": workspaceID}
+	if _, err := config.TasksCollection.DeleteMany(ctx, byWorkspace); err != nil {
+		return err
+	}
+	if _, err := config.WorkspaceInvitationsCollection.DeleteMany(ctx, byWorkspace); err != nil {
+		return err
+	}
+	if _, err := config.WorkspaceMembersCollection.DeleteMany(ctx, byWorkspace); err != nil {
+		return err
+	}
+	if err := DeleteWorkspaceServiceAccounts(ctx, workspaceID); err != nil {
+		return err
+	}
+	_, err := config.WorkspacesCollection.DeleteOne(ctx, bson.M{"_id": workspaceID})
+	return err
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+)
+
+// EmailChangeTokenTTL is how long the confirmation link for a new address stays valid
+const EmailChangeTokenTTL = 24 * time.Hour
+
+var (
+	ErrEmailTaken         = errors.New("email already in use")
+	ErrEmailChangeInvalid = errors.New("invalid or expired email change link")
+)
+
+// RequestEmailChange - Stores newEmail as pending and emails a confirmation
+// link to it. The current address stays in use until the link is opened.
+func RequestEmailChange(ctx context.Context, user *models.User, newEmail string) error {
+	newEmail = utils.NormalizeEmail(newEmail)
+
+	if err := ensureEmailAvailable(ctx, newEmail, user.ID); err != nil {
+		return err
+	}
+
+	_, err := config.UsersCollection.UpdateOne(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{"pending_email": newEmail, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+
+	token, err := utils.GenerateActionToken(utils.PurposeEmailChange, user.ID.Hex(), newEmail, EmailChangeTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/auth/email/confirm?token=%s", config.AppBaseURL(), url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\nPlease confirm your new email address by opening the link below:\n\n%s\n\nThe link expires in %s. If you didn't ask for this change, you can ignore this email.\n",
+		user.Name, link, EmailChangeTokenTTL)
+
+	return SendMail(newEmail, "Confirm your new email address", body)
+}
+
+// ConfirmEmailChange - Switches the account to the pending address named in the
+// token and marks it verified. The previous address is notified. Returns the
+// account's ID.
+func ConfirmEmailChange(ctx context.Context, token string) (primitive.ObjectID, error) {
+	claims, err := utils.VerifyActionToken(token, utils.PurposeEmailChange)
+	if err != nil {
+		return primitive.NilObjectID, ErrEmailChangeInvalid
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.Subject)
+	if err != nil {
+		return primitive.NilObjectID, ErrEmailChangeInvalid
+	}
+
+	if err := ensureEmailAvailable(ctx, claims.Email, userID); err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	// Matching on pending_email makes links for a superseded request useless
+	now := time.Now()
+	var previous models.User
+	err = config.UsersCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": userID, "pending_email": claims.Email},
+		bson.M{
+			"$set":   bson.M{"email": claims.Email, "email_verified": true, "email_verified_at": now, "updated_at": now},
+			"$unset": bson.M{"pending_email": ""},
+		},
+	).Decode(&previous)
+	if err == mongo.ErrNoDocuments {
+		return primitive.NilObjectID, ErrEmailChangeInvalid
+	} else if mongo.IsDuplicateKeyError(err) {
+		return primitive.NilObjectID, ErrEmailTaken
+	} else if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nThe email address on your account was changed to %s. If you didn't make this change, reset your password and contact support.\n",
+		previous.Name, claims.Email)
+	if err := SendMail(previous.Email, "Your email address was changed", body); err != nil {
+		log.Println("Failed to send email change notice:", err)
+	}
+
+	return userID, nil
+}
+
+// NormalizeStoredEmails - Lowercases and trims email addresses stored before
+// addresses were normalised. Addresses that would collide with another
+// account's can't sign in once lookups are normalised, so they fail the
+// migration until an admin merges or renames the accounts. The others are
+// still normalised. Safe to run on every start.
+func NormalizeStoredEmails(ctx context.Context) error {
+	cursor, err := config.UsersCollection.Find(ctx,
+		bson.M{"$expr": bson.M{"$ne": bson.A{"$email", bson.M{"$toLower": bson.M{"$trim": bson.M{"input": "$email"}}}}}},
+		options.Find().SetProjection(bson.M{"_id": 1, "email": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return err
+	}
+
+	var collisions []string
+	for _, user := range users {
+		normalized := utils.NormalizeEmail(user.Email)
+		_, err := config.UsersCollection.UpdateOne(ctx,
+			bson.M{"_id": user.ID},
+			bson.M{"$set": bson.M{"email": normalized, "updated_at": time.Now()}},
+		)
+		if mongo.IsDuplicateKeyError(err) {
+			collisions = append(collisions, fmt.Sprintf("user %s (%s)", user.ID.Hex(), user.Email))
+			continue
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if len(collisions) > 0 {
+		return fmt.Errorf("%d accounts have an email that differs only in case or spacing from another account's, merge or rename them: %s",
+			len(collisions), strings.Join(collisions, ", "))
+	}
+	return nil
+}
+
+// ensureEmailAvailable returns ErrEmailTaken if another account uses email
+func ensureEmailAvailable(ctx context.Context, email string, userID primitive.ObjectID) error {
+	count, err := config.UsersCollection.CountDocuments(ctx, bson.M{"email": email, "_id": bson.M{"$ne": userID}})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrEmailTaken
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestNormalizeStoredEmailsFailsOnCollision(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("collision", func(mt *mtest.T) {
+		useMockCollections(mt)
+
+		normalizedID, collidingID := primitive.NewObjectID(), primitive.NewObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "taskapp.users", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: normalizedID}, {Key: "email", Value: "Alice@Example.com"}},
+				bson.D{{Key: "_id", Value: collidingID}, {Key: "email", Value: "BOB@example.com"}},
+			),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "E11000 duplicate key error"}),
+		)
+
+		err := NormalizeStoredEmails(context.Background())
+		if err == nil {
+			mt.Fatal("expected the collision to fail the migration")
+		}
+		if !strings.Contains(err.Error(), collidingID.Hex()) || strings.Contains(err.Error(), normalizedID.Hex()) {
+			mt.Fatalf("error should name only the colliding account: %v", err)
+		}
+
+		// The account without a collision is still normalised
+		update := startedCommand(mt, "update")
+		if email := update.Lookup("updates", "0", "u", "$set", "email").StringValue(); email != "alice@example.com" {
+			mt.Fatalf("first account set to %q", email)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend/config"
+	"backend/models"
+)
+
+// AuditFilter narrows ListAuditEvents; zero values match everything
+type AuditFilter struct {
+	UserID primitive.ObjectID // Matches events where the user is the actor or the target
+	Event  string
+	From   time.Time
+	To     time.Time
+}
+
+// RecordAuditEvent - Appends an event to the audit log. There is deliberately
+// no way to update or delete events.
+func RecordAuditEvent(ctx context.Context, event *models.AuditEvent) error {
+	event.ID = primitive.NewObjectID()
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	_, err := config.AuditEventsCollection.InsertOne(ctx, event)
+	return err
+}
+
+// ListAuditEvents - Returns one page of matching events, newest first, along
+// with the total number of matches
+func ListAuditEvents(ctx context.Context, filter AuditFilter, page, perPage int) ([]models.AuditEvent, int64, error) {
+	query := bson.M{}
+	if !filter.UserID.IsZero() {
+		query["$or"] = []bson.M{{"actor_id": filter.UserID}, {"target_user_id": filter.UserID}}
+	}
+	if filter.Event != "" {
+		query["event"] = filter.Event
+	}
+	if !filter.From.IsZero() || !filter.To.IsZero() {
+		createdAt := bson.M{}
+		if !filter.From.IsZero() {
+			createdAt["$gte"] = filter.From
+		}
+		if !filter.To.IsZero() {
+			createdAt["$lt"] = filter.To
+		}
+		query["created_at"] = createdAt
+	}
+
+	total, err := config.AuditEventsCollection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cursor, err := config.AuditEventsCollection.Find(ctx, query, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((page-1)*perPage)).
+		SetLimit(int64(perPage)),
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	events := []models.AuditEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
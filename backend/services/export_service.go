@@ -0,0 +1,162 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend/config"
+	"backend/models"
+)
+
+// exportComment is one comment the user wrote, with the task it belongs to
+type exportComment struct {
+	TaskID    primitive.ObjectID `json:"task_id" bson:"task_id"`
+	TaskTitle string             `json:"task_title" bson:"task_title"`
+	Text      string             `json:"text" bson:"text"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// exportTask is a task the user created or is assigned to, reduced to the
+// task itself. Other people's comments, assignments and edits are their
+// personal data, not the requester's; the user's own comments are in
+// comments.json.
+type exportTask struct {
+	ID          primitive.ObjectID   `json:"id" bson:"_id"`
+	WorkspaceID primitive.ObjectID   `json:"workspace_id" bson:"workspace_id"`
+	Title       string               `json:"title" bson:"title"`
+	Description string               `json:"description,omitempty" bson:"description,omitempty"`
+	Status      models.TaskStatus    `json:"status" bson:"status"`
+	Priority    models.PriorityLevel `json:"priority" bson:"priority"`
+	DueDate     *time.Time           `json:"due_date,omitempty" bson:"due_date,omitempty"`
+	CreatedAt   time.Time            `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at" bson:"updated_at"`
+}
+
+var exportTaskProjection = bson.M{
+	"workspace_id": 1,
+	"title":        1,
+	"description":  1,
+	"status":       1,
+	"priority":     1,
+	"due_date":     1,
+	"created_at":   1,
+	"updated_at":   1,
+}
+
+// WriteUserExport - Writes a ZIP archive of the user's personal data to w, one
+// JSON file per kind of record. Secrets such as password hashes, TOTP secrets
+// and token hashes are never included.
+func WriteUserExport(ctx context.Context, user *models.User, w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	profile := map[string]interface{}{
+		"id":                 user.ID.Hex(),
+		"name":               user.Name,
+		"email":              user.Email,
+		"email_verified":     user.EmailVerified,
+		"email_verified_at":  user.EmailVerifiedAt,
+		"avatar_url":         user.AvatarURL,
+		"timezone":           user.Timezone,
+		"locale":             user.Locale,
+		"role":               user.Role,
+		"two_factor_enabled": user.TwoFactorEnabled,
+		"oidc_issuer":        user.OIDCIssuer,
+		"created_at":         user.CreatedAt,
+		"updated_at":         user.UpdatedAt,
+		"exported_at":        time.Now(),
+	}
+	if err := writeExportFile(archive, "profile.json", profile); err != nil {
+		return err
+	}
+
+	var memberships []models.WorkspaceMember
+	if err := findAll(ctx, config.WorkspaceMembersCollection, bson.M{"user_id": user.ID}, &memberships); err != nil {
+		return err
+	}
+	if err := writeExportFile(archive, "workspace_memberships.json", memberships); err != nil {
+		return err
+	}
+
+	created := []exportTask{}
+	if err := findAll(ctx, config.TasksCollection, bson.M{"created_by": user.ID}, &created, options.Find().SetProjection(exportTaskProjection)); err != nil {
+		return err
+	}
+	if err := writeExportFile(archive, "tasks_created.json", created); err != nil {
+		return err
+	}
+
+	assigned := []exportTask{}
+	if err := findAll(ctx, config.TasksCollection, bson.M{"assigned_to": user.ID}, &assigned, options.Find().SetProjection(exportTaskProjection)); err != nil {
+		return err
+	}
+	if err := writeExportFile(archive, "tasks_assigned.json", assigned); err != nil {
+		return err
+	}
+
+	cursor, err := config.TasksCollection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"comments.user_id": user.ID}}},
+		{{Key: "$unwind", Value: "$comments"}},
+		{{Key: "$match", Value: bson.M{"comments.user_id": user.ID}}},
+		{{Key: "$project", Value: bson.M{
+			"task_id":    "$_id",
+			"task_title": "$title",
+			"text":       "$comments.text",
+			"created_at": "$comments.created_at",
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+	comments := []exportComment{}
+	if err := cursor.All(ctx, &comments); err != nil {
+		return err
+	}
+	if err := writeExportFile(archive, "comments.json", comments); err != nil {
+		return err
+	}
+
+	var sessions []models.Session
+	if err := findAll(ctx, config.SessionsCollection, bson.M{"user_id": user.ID}, &sessions); err != nil {
+		return err
+	}
+	if err := writeExportFile(archive, "sessions.json", sessions); err != nil {
+		return err
+	}
+
+	var tokens []models.PersonalAccessToken
+	if err := findAll(ctx, config.PersonalAccessTokensCollection, bson.M{"user_id": user.ID}, &tokens); err != nil {
+		return err
+	}
+	if err := writeExportFile(archive, "personal_access_tokens.json", tokens); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+func findAll(ctx context.Context, collection *mongo.Collection, filter bson.M, out interface{}, opts ...*options.FindOptions) error {
+	cursor, err := collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, out)
+}
+
+func writeExportFile(archive *zip.Writer, name string, v interface{}) error {
+	f, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+)
+
+var (
+	ErrInvitationInvalid       = errors.New("invalid, expired or revoked invitation")
+	ErrInvitationEmailMismatch = errors.New("invitation was sent to a different email address")
+	// The backend has no page to open an invite link in, so it must point at the frontend
+	ErrInvitationsNeedFrontend = errors.New("FRONTEND_URL must be set to send invitations")
+)
+
+// CreateInvitation - Records an invitation and emails the signed link to the
+// invitee. The invitation is withdrawn again if the email can't be sent.
+func CreateInvitation(ctx context.Context, workspace *models.Workspace, inviter *models.User, email string, role models.Role, ttl time.Duration) (*models.WorkspaceInvitation, error) {
+	frontendURL := config.GetEnv("FRONTEND_URL", "")
+	if frontendURL == "" {
+		return nil, ErrInvitationsNeedFrontend
+	}
+
+	now := time.Now()
+	invitation := &models.WorkspaceInvitation{
+		ID:          primitive.NewObjectID(),
+		WorkspaceID: workspace.ID,
+		Email:       utils.NormalizeEmail(email),
+		Role:        role,
+		InvitedBy:   inviter.ID,
+		ExpiresAt:   now.Add(ttl),
+		CreatedAt:   now,
+	}
+	if _, err := config.WorkspaceInvitationsCollection.InsertOne(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateActionToken(utils.PurposeWorkspaceInvite, invitation.ID.Hex(), invitation.Email, ttl)
+	if err != nil {
+		withdrawInvitation(invitation.ID)
+		return nil, err
+	}
+
+	link := fmt.Sprintf("%s/invitations/accept?token=%s", frontendURL, url.QueryEscape(token))
+	body := fmt.Sprintf("Hi,\n\n%s has invited you to join the workspace \"%s\" as %s.\n\nAccept the invitation here:\n\n%s\n\nIf you don't have an account yet, you can create one from the same link. The invitation expires on %s.\n",
+		inviter.Name, workspace.Name, role, link, invitation.ExpiresAt.Format(time.RFC1123))
+
+	if err := SendMail(invitation.Email, "You're invited to "+workspace.Name, body); err != nil {
+		withdrawInvitation(invitation.ID)
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// withdrawInvitation deletes an invitation whose link never reached the invitee
+func withdrawInvitation(invitationID primitive.ObjectID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := config.WorkspaceInvitationsCollection.DeleteOne(ctx, bson.M{"_id": invitationID}); err != nil {
+		log.Println("Failed to withdraw unsent invitation:", err)
+	}
+}
+
+// CheckInvitation - Validates an invite token and returns the pending
+// invitation, which must have been sent to email
+func CheckInvitation(ctx context.Context, token, email string) (*models.WorkspaceInvitation, error) {
+	claims, err := utils.VerifyActionToken(token, utils.PurposeWorkspaceInvite)
+	if err != nil {
+		return nil, ErrInvitationInvalid
+	}
+
+	invitationID, err := primitive.ObjectIDFromHex(claims.Subject)
+	if err != nil {
+		return nil, ErrInvitationInvalid
+	}
+
+	var invitation models.WorkspaceInvitation
+	err = config.WorkspaceInvitationsCollection.FindOne(ctx, pendingInvitationFilter(invitationID)).Decode(&invitation)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrInvitationInvalid
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(invitation.Email, strings.TrimSpace(email)) {
+		return nil, ErrInvitationEmailMismatch
+	}
+
+	return &invitation, nil
+}
+
+// AcceptInvitation - Claims the invitation for the user and adds the membership
+func AcceptInvitation(ctx context.Context, token string, user *models.User) (*models.WorkspaceInvitation, error) {
+	invitation, err := CheckInvitation(ctx, token, user.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	// Claim atomically so the same invitation can't be accepted twice
+	now := time.Now()
+	err = config.WorkspaceInvitationsCollection.FindOneAndUpdate(ctx,
+		pendingInvitationFilter(invitation.ID),
+		bson.M{"$set": bson.M{"accepted_at": now, "accepted_by": user.ID}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(invitation)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrInvitationInvalid
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Accepting while already a member keeps the existing role
+	if _, err := AddWorkspaceMember(ctx, invitation.WorkspaceID, user.ID, invitation.Role); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// ListPendingInvitations - Returns a workspace's open invitations, newest first
+func ListPendingInvitations(ctx context.Context, workspaceID primitive.ObjectID) ([]models.WorkspaceInvitation, error) {
+	cursor, err := config.WorkspaceInvitationsCollection.Find(ctx,
+		bson.M{
+			"workspace_id": workspaceID,
+			"accepted_at":  bson.M{"$exists": false},
+			"revoked_at":   bson.M{"$exists": false},
+			"expires_at":   bson.M{"$gt": time.Now()},
+		},
+		options.Find().SetSort(bson.M{"created_at": -1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations := []models.WorkspaceInvitation{}
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// RevokeInvitation - Cancels a pending invitation; false if none matched
+func RevokeInvitation(ctx context.Context, workspaceID, invitationID primitive.ObjectID) (bool, error) {
+	filter := pendingInvitationFilter(invitationID)
+	filter["workspace_id"] = workspaceID
+
+	result, err := config.WorkspaceInvitationsCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func pendingInvitationFilter(invitationID primitive.ObjectID) bson.M {
+	return bson.M{
+		"_id":         invitationID,
+		"accepted_at": bson.M{"$exists": false},
+		"revoked_at":  bson.M{"$exists": false},
+		"expires_at":  bson.M{"$gt": time.Now()},
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"backend/models"
+	"backend/utils"
+)
+
+// failingMailer refuses every email
+type failingMailer struct{}
+
+func (failingMailer) Send(to, subject, body string) error {
+	return errors.New("relay unavailable")
+}
+
+func TestCreateInvitationRequiresFrontendURL(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("unset", func(mt *mtest.T) {
+		useMockCollections(mt)
+		mt.Setenv("FRONTEND_URL", "")
+
+		workspace := &models.Workspace{ID: primitive.NewObjectID(), Name: "Team"}
+		inviter := &models.User{ID: primitive.NewObjectID(), Name: "Inviter"}
+		_, err := CreateInvitation(context.Background(), workspace, inviter, "invitee@example.com", models.RoleMember, time.Hour)
+		if err != ErrInvitationsNeedFrontend {
+			mt.Fatalf("got %v, want ErrInvitationsNeedFrontend", err)
+		}
+		if events := mt.GetAllStartedEvents(); len(events) != 0 {
+			mt.Fatalf("sent %d commands, want none", len(events))
+		}
+	})
+}
+
+func TestCreateInvitationWithdrawsUnsentInvitation(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("send fails", func(mt *mtest.T) {
+		useMockCollections(mt)
+		mt.Setenv("FRONTEND_URL", "https://app.example.com")
+		mt.Setenv("JWT_SECRET", "0123456789abcdef0123456789abcdef")
+		if err := utils.LoadSigningKeys(); err != nil {
+			mt.Fatal(err)
+		}
+		SetMailer(failingMailer{})
+		defer SetMailer(LogMailer{})
+
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(), // Insert invitation
+			mtest.CreateSuccessResponse(), // Delete it again
+		)
+
+		workspace := &models.Workspace{ID: primitive.NewObjectID(), Name: "Team"}
+		inviter := &models.User{ID: primitive.NewObjectID(), Name: "Inviter"}
+		if _, err := CreateInvitation(context.Background(), workspace, inviter, "invitee@example.com", models.RoleMember, time.Hour); err == nil {
+			mt.Fatal("expected the send failure to be returned")
+		}
+
+		inserted := startedCommand(mt, "insert").Lookup("documents", "0", "_id").ObjectID()
+		deleted := startedCommand(mt, "delete").Lookup("deletes", "0", "q", "_id").ObjectID()
+		if deleted != inserted {
+			mt.Fatalf("deleted %v, want the unsent invitation %v", deleted, inserted)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend/config"
+	"backend/models"
+)
+
+// failureWindow is how long a failure counter survives without new failures
+const failureWindow = 24 * time.Hour
+
+func accountLockKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// ipLockKey counts failures per client IP. Behind a reverse proxy this needs
+// TRUSTED_PROXIES (see config.ProxyConfig), or all clients share one counter.
+func ipLockKey(ip string) string {
+	return "ip:" + ip
+}
+
+// LoginRetryAfter - Returns how long the caller must wait before trying to log
+// in again, or zero when neither the account nor the IP is locked
+func LoginRetryAfter(ctx context.Context, email, ip string) (time.Duration, error) {
+	cursor, err := config.LoginAttemptsCollection.Find(ctx, bson.M{
+		"key":          bson.M{"$in": []string{accountLockKey(email), ipLockKey(ip)}},
+		"locked_until": bson.M{"$gt": time.Now()},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var wait time.Duration
+	for cursor.Next(ctx) {
+		var attempt models.LoginAttempt
+		if err := cursor.Decode(&attempt); err != nil {
+			return 0, err
+		}
+		if remaining := time.Until(*attempt.LockedUntil); remaining > wait {
+			wait = remaining
+		}
+	}
+
+	return wait, cursor.Err()
+}
+
+// RecordLoginFailure - Counts a failed login against the account and the IP.
+// Returns the lockout that now applies, if any.
+func RecordLoginFailure(ctx context.Context, email, ip string) (time.Duration, error) {
+	accountWait, err := recordFailure(ctx, accountLockKey(email), config.GetEnvInt("LOGIN_MAX_FAILURES", 5))
+	if err != nil {
+		return 0, err
+	}
+
+	ipWait, err := recordFailure(ctx, ipLockKey(ip), config.GetEnvInt("LOGIN_IP_MAX_FAILURES", 20))
+	if err != nil {
+		return 0, err
+	}
+
+	if ipWait > accountWait {
+		return ipWait, nil
+	}
+	return accountWait, nil
+}
+
+// recordFailure increments the counter for key and, once maxFailures is
+// reached, locks it for an exponentially growing period
+func recordFailure(ctx context.Context, key string, maxFailures int) (time.Duration, error) {
+	now := time.Now()
+
+	var attempt models.LoginAttempt
+	err := config.LoginAttemptsCollection.FindOneAndUpdate(ctx,
+		bson.M{"key": key},
+		bson.M{
+			"$inc": bson.M{"failures": 1},
+			"$set": bson.M{"last_failure_at": now, "expires_at": now.Add(failureWindow)},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&attempt)
+	if err != nil {
+		return 0, err
+	}
+
+	if attempt.Failures < maxFailures {
+		return 0, nil
+	}
+
+	lockout := lockoutDuration(attempt.Failures - maxFailures)
+	_, err = config.LoginAttemptsCollection.UpdateOne(ctx,
+		bson.M{"key": key},
+		bson.M{"$set": bson.M{"locked_until": now.Add(lockout)}},
+	)
+	return lockout, err
+}
+
+// lockoutDuration doubles the base lockout for every failure past the limit
+func lockoutDuration(excess int) time.Duration {
+	base := config.GetEnvDuration("LOGIN_LOCKOUT_BASE", time.Minute)
+	maxLockout := config.GetEnvDuration("LOGIN_LOCKOUT_MAX", time.Hour)
+
+	lockout := base
+	for i := 0; i < excess && lockout < maxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > maxLockout {
+		lockout = maxLockout
+	}
+	return lockout
+}
+
+// ResetLoginFailures - Clears the account counter after a successful login
+func ResetLoginFailures(ctx context.Context, email string) error {
+	_, err := config.LoginAttemptsCollection.DeleteOne(ctx, bson.M{"key": accountLockKey(email)})
+	return err
+}
+
+// UnlockAccount - Lifts an account lockout and resets its failure counter
+func UnlockAccount(ctx context.Context, email string) error {
+	return ResetLoginFailures(ctx, email)
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+)
+
+const (
+	// MagicLinkTTL is how long an emailed login link stays valid
+	MagicLinkTTL = 15 * time.Minute
+	// MagicLinkResendInterval throttles how often one address can be sent a link
+	MagicLinkResendInterval = time.Minute
+)
+
+var ErrMagicLinkInvalid = errors.New("invalid or expired login link")
+
+// SendMagicLink - Emails a login link to the account with this address, unless
+// one was sent within MagicLinkResendInterval. Disabled accounts and accounts
+// awaiting deletion are skipped. Earlier unused links stop working.
+func SendMagicLink(ctx context.Context, email, ip string) error {
+	now := time.Now()
+	var user models.User
+	err := config.UsersCollection.FindOneAndUpdate(ctx,
+		bson.M{
+			"email":                  email,
+			"disabled":               bson.M{"$ne": true},
+			"deletion_scheduled_for": bson.M{"$exists": false},
+			"$or": []bson.M{
+				{"magic_link_sent_at": bson.M{"$exists": false}},
+				{"magic_link_sent_at": bson.M{"$lt": now.Add(-MagicLinkResendInterval)}},
+			},
+		},
+		bson.M{"$set": bson.M{"magic_link_sent_at": now}},
+	).Decode(&user)
+	if err != nil {
+		return err
+	}
+
+	// The token is signed so forged links are rejected before any lookup, and
+	// bound to the address so a link sent before an email change stops working
+	raw, err := utils.GenerateActionToken(utils.PurposeMagicLink, user.ID.Hex(), user.Email, MagicLinkTTL)
+	if err != nil {
+		return err
+	}
+
+	if _, err := config.MagicLinksCollection.DeleteMany(ctx, bson.M{"user_id": user.ID, "used_at": bson.M{"$exists": false}}); err != nil {
+		return err
+	}
+
+	_, err = config.MagicLinksCollection.InsertOne(ctx, models.MagicLink{
+		ID:        primitive.NewObjectID(),
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(raw),
+		RequestIP: ip,
+		ExpiresAt: now.Add(MagicLinkTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/magic-link?token=%s", config.FrontendURL(), url.QueryEscape(raw))
+	body := fmt.Sprintf("Hi %s,\n\nOpen the link below to sign in:\n\n%s\n\nThe link expires in %s and can only be used once. If you didn't ask for it, you can ignore this email.\n",
+		user.Name, link, MagicLinkTTL)
+
+	return SendMail(user.Email, "Your sign-in link", body)
+}
+
+// ConsumeMagicLink - Marks a login link as used and returns its user. The link
+// is claimed atomically so it can only ever be used once.
+func ConsumeMagicLink(ctx context.Context, raw string) (*models.User, error) {
+	claims, err := utils.VerifyActionToken(raw, utils.PurposeMagicLink)
+	if err != nil {
+		return nil, ErrMagicLinkInvalid
+	}
+
+	var link models.MagicLink
+	err = config.MagicLinksCollection.FindOneAndUpdate(ctx,
+		bson.M{
+			"token_hash": utils.HashToken(raw),
+			"used_at":    bson.M{"$exists": false},
+			"expires_at": bson.M{"$gt": time.Now()},
+		},
+		bson.M{"$set": bson.M{"used_at": time.Now()}},
+	).Decode(&link)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrMagicLinkInvalid
+	} else if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	err = config.UsersCollection.FindOne(ctx, bson.M{"_id": link.UserID, "email": claims.Email}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrMagicLinkInvalid
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Opening the link proves the address, just like the verification email
+	if !user.EmailVerified {
+		now := time.Now()
+		_, err := config.UsersCollection.UpdateOne(ctx,
+			bson.M{"_id": user.ID},
+			bson.M{"$set": bson.M{"email_verified": true, "email_verified_at": now, "updated_at": now}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		user.EmailVerified = true
+		user.EmailVerifiedAt = &now
+	}
+
+	return &user, nil
+}
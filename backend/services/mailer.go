@@ -0,0 +1,99 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"backend/config"
+)
+
+// Mailer delivers transactional email such as verification links
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+var mailer Mailer = LogMailer{}
+
+var ErrMailerNotConfigured = errors.New("SMTP_HOST is not set; set MAIL_LOG_ONLY=true to only log emails in development")
+
+// SetupMailer - Selects SMTP delivery when SMTP_HOST is configured. Without it
+// emails are only logged, which must be asked for with MAIL_LOG_ONLY=true so
+// a missing setting can't silently stop delivery in production.
+func SetupMailer() error {
+	host := config.GetEnv("SMTP_HOST", "")
+	if host == "" {
+		if !config.GetEnvBool("MAIL_LOG_ONLY", false) {
+			return ErrMailerNotConfigured
+		}
+		log.Println("MAIL_LOG_ONLY set, emails will be logged instead of sent")
+		SetMailer(LogMailer{})
+		return nil
+	}
+
+	SetMailer(&SMTPMailer{
+		Host:     host,
+		Port:     config.GetEnv("SMTP_PORT", "587"),
+		Username: config.GetEnv("SMTP_USERNAME", ""),
+		Password: config.GetEnv("SMTP_PASSWORD", ""),
+		From:     config.GetEnv("SMTP_FROM", "no-reply@localhost"),
+	})
+	return nil
+}
+
+// SetMailer - Replaces the mailer, e.g. with a capture mailer in tests
+func SetMailer(m Mailer) {
+	mailer = m
+}
+
+// SendMail - Delivers an email through the configured mailer
+func SendMail(to, subject, body string) error {
+	return mailer.Send(to, subject, body)
+}
+
+// SMTPMailer sends plain text email through an SMTP relay. Without a username
+// no AUTH is attempted, which suits local capture servers like MailHog.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	// Refuse header injection through user supplied addresses
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("invalid email header value")
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	headers := []string{
+		"From: " + m.From,
+		"To: " + to,
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+	msg := strings.Join(headers, "\r\n") + "\r\n\r\n" + body
+
+	return smtp.SendMail(net.JoinHostPort(m.Host, m.Port), auth, m.From, []string{to}, []byte(msg))
+}
+
+// LogMailer logs the recipient and subject of emails instead of sending them.
+// Bodies are left out because they carry live reset, sign-in and invite links.
+type LogMailer struct{}
+
+func (LogMailer) Send(to, subject, body string) error {
+	log.Printf("Email to %s: %s", to, subject)
+	return nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureSMTP is a minimal SMTP server that accepts one message and records
+// the envelope and data it was sent
+type captureSMTP struct {
+	listener net.Listener
+	from     string
+	rcpt     []string
+	data     chan string
+}
+
+func newCaptureSMTP(t *testing.T) *captureSMTP {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &captureSMTP{listener: listener, data: make(chan string, 1)}
+	go server.serve()
+	return server
+}
+
+func (s *captureSMTP) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 capture ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.TrimRight(line, "\r\n")
+		upper := strings.ToUpper(command)
+
+		switch {
+		case strings.HasPrefix(upper, "EHLO"), strings.HasPrefix(upper, "HELO"):
+			reply("250 capture")
+		case strings.HasPrefix(upper, "MAIL FROM:"):
+			s.from = strings.Trim(command[len("MAIL FROM:"):], "<> ")
+			reply("250 OK")
+		case strings.HasPrefix(upper, "RCPT TO:"):
+			s.rcpt = append(s.rcpt, strings.Trim(command[len("RCPT TO:"):], "<> "))
+			reply("250 OK")
+		case upper == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var message strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				message.WriteString(dataLine)
+			}
+			s.data <- message.String()
+			reply("250 OK")
+		case upper == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPMailerSendsMessage(t *testing.T) {
+	server := newCaptureSMTP(t)
+	host, port, _ := net.SplitHostPort(server.listener.Addr().String())
+
+	m := &SMTPMailer{Host: host, Port: port, From: "no-reply@example.com"}
+	if err := m.Send("user@example.com", "Verify your email", "Open https://example.com/verify?token=abc"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	message := <-server.data
+	if server.from != "no-reply@example.com" {
+		t.Fatalf("MAIL FROM = %q", server.from)
+	}
+	if len(server.rcpt) != 1 || server.rcpt[0] != "user@example.com" {
+		t.Fatalf("RCPT TO = %v", server.rcpt)
+	}
+	for _, want := range []string{
+		"From: no-reply@example.com\r\n",
+		"To: user@example.com\r\n",
+		"Subject: Verify your email\r\n",
+		"Content-Type: text/plain; charset=UTF-8\r\n",
+		"\r\n\r\nOpen https://example.com/verify?token=abc",
+	} {
+		if !strings.Contains(message, want) {
+			t.Fatalf("message missing %q:\n%s", want, message)
+		}
+	}
+}
+
+func TestSMTPMailerRejectsHeaderInjection(t *testing.T) {
+	m := &SMTPMailer{Host: "127.0.0.1", Port: "1", From: "no-reply@example.com"}
+	if err := m.Send("user@example.com\r\nBcc: attacker@example.com", "Hi", "body"); err == nil {
+		t.Fatal("expected a recipient containing CRLF to be refused")
+	}
+}
+
+func TestLogMailerOmitsBody(t *testing.T) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	LogMailer{}.Send("user@example.com", "Reset your password", "https://example.com/reset?token=secret")
+
+	if !strings.Contains(output.String(), "user@example.com") || !strings.Contains(output.String(), "Reset your password") {
+		t.Fatalf("recipient and subject not logged: %q", output.String())
+	}
+	if strings.Contains(output.String(), "secret") {
+		t.Fatalf("body was logged: %q", output.String())
+	}
+}
+
+func TestSetupMailerRequiresExplicitLogOnly(t *testing.T) {
+	t.Setenv("SMTP_HOST", "")
+	t.Setenv("MAIL_LOG_ONLY", "")
+	if err := SetupMailer(); err != ErrMailerNotConfigured {
+		t.Fatalf("got %v, want ErrMailerNotConfigured", err)
+	}
+
+	t.Setenv("MAIL_LOG_ONLY", "true")
+	if err := SetupMailer(); err != nil {
+		t.Fatalf("SetupMailer with MAIL_LOG_ONLY: %v", err)
+	}
+}
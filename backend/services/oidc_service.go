@@ -0,0 +1,404 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+)
+
+// providerCacheTTL controls how often discovery and JWKS documents are refetched
+const providerCacheTTL = time.Hour
+
+var (
+	ErrOIDCNotConfigured = errors.New("OIDC is not configured")
+	ErrOIDCInvalidToken  = errors.New("invalid ID token")
+	// ErrOIDCEmailNotVerified means the provider didn't vouch for the email
+	ErrOIDCEmailNotVerified = errors.New("identity provider did not verify the email address")
+	ErrOIDCUserNotFound     = errors.New("no account exists for this identity")
+	ErrOIDCAccountLinked    = errors.New("account is linked to a different identity")
+)
+
+// OIDCConfig is read from the environment on each use so tests can point it
+// at a local mock identity provider
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       string
+}
+
+// LoadOIDCConfig - Returns the OIDC settings, or ErrOIDCNotConfigured
+func LoadOIDCConfig() (*OIDCConfig, error) {
+	cfg := &OIDCConfig{
+		Issuer:       strings.TrimSuffix(config.GetEnv("OIDC_ISSUER", ""), "/"),
+		ClientID:     config.GetEnv("OIDC_CLIENT_ID", ""),
+		ClientSecret: config.GetEnv("OIDC_CLIENT_SECRET", ""),
+		RedirectURL:  config.GetEnv("OIDC_REDIRECT_URL", config.AppBaseURL()+"/auth/oidc/callback"),
+		Scopes:       config.GetEnv("OIDC_SCOPES", "openid email profile"),
+	}
+	if cfg.Issuer == "" || cfg.ClientID == "" {
+		return nil, ErrOIDCNotConfigured
+	}
+	return cfg, nil
+}
+
+// oidcDiscovery is the subset of the provider metadata document we use
+type oidcDiscovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	SigningAlgValues      []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// IDTokenClaims are the ID token claims needed to find or create a user.
+// EmailVerified is left raw because some providers send it as a string.
+type IDTokenClaims struct {
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"`
+	Name          string      `json:"name"`
+	Nonce         string      `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// IsEmailVerified interprets email_verified whether it is a bool or a string
+func (c *IDTokenClaims) IsEmailVerified() bool {
+	switch v := c.EmailVerified.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+var oidcHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+var oidcCache struct {
+	sync.Mutex
+	issuer    string
+	discovery *oidcDiscovery
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// OIDCAuthorizationURL - Builds the provider redirect for the authorization code + PKCE flow
+func OIDCAuthorizationURL(ctx context.Context, cfg *OIDCConfig, state, nonce, codeVerifier string) (string, error) {
+	discovery, err := discoverProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", cfg.ClientID)
+	params.Set("redirect_uri", cfg.RedirectURL)
+	params.Set("scope", cfg.Scopes)
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// ExchangeOIDCCode - Redeems the authorization code and returns the validated ID token claims
+func ExchangeOIDCCode(ctx context.Context, cfg *OIDCConfig, code, codeVerifier, nonce string) (*IDTokenClaims, error) {
+	discovery, err := discoverProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", cfg.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	form.Set("client_id", cfg.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+	}
+
+	resp, err := oidcHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %d", resp.StatusCode)
+	}
+
+	var tokenResponse struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return nil, err
+	}
+	if tokenResponse.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	return verifyIDToken(ctx, cfg, discovery, tokenResponse.IDToken, nonce)
+}
+
+// verifyIDToken checks the signature against the provider JWKS and validates
+// issuer, audience, expiry and nonce
+func verifyIDToken(ctx context.Context, cfg *OIDCConfig, discovery *oidcDiscovery, raw, nonce string) (*IDTokenClaims, error) {
+	methods := []string{"RS256", "ES256"}
+	if len(discovery.SigningAlgValues) > 0 {
+		methods = intersect(methods, discovery.SigningAlgValues)
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("provider offers no supported ID token signing algorithm")
+	}
+
+	claims := new(IDTokenClaims)
+	token, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return providerKey(ctx, cfg.Issuer, kid)
+	}, jwt.WithValidMethods(methods))
+	if err != nil || !token.Valid {
+		return nil, ErrOIDCInvalidToken
+	}
+
+	if claims.Issuer != discovery.Issuer || !claims.VerifyAudience(cfg.ClientID, true) {
+		return nil, ErrOIDCInvalidToken
+	}
+	if claims.ExpiresAt == nil || claims.Subject == "" {
+		return nil, ErrOIDCInvalidToken
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return nil, ErrOIDCInvalidToken
+	}
+
+	return claims, nil
+}
+
+// discoverProvider fetches and caches the provider's openid-configuration
+func discoverProvider(ctx context.Context, issuer string) (*oidcDiscovery, error) {
+	oidcCache.Lock()
+	defer oidcCache.Unlock()
+
+	if oidcCache.issuer == issuer && oidcCache.discovery != nil && time.Since(oidcCache.fetchedAt) < providerCacheTTL {
+		return oidcCache.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	if err := fetchJSON(ctx, issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != issuer {
+		return nil, errors.New("discovery issuer does not match OIDC_ISSUER")
+	}
+
+	oidcCache.issuer = issuer
+	oidcCache.discovery = &discovery
+	oidcCache.keys = nil
+	oidcCache.fetchedAt = time.Now()
+	return &discovery, nil
+}
+
+// providerKey returns the verification key for kid, refetching the JWKS once
+// when the kid is unknown so provider key rotation is picked up
+func providerKey(ctx context.Context, issuer, kid string) (interface{}, error) {
+	discovery, err := discoverProvider(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	oidcCache.Lock()
+	defer oidcCache.Unlock()
+
+	if key, ok := oidcCache.keys[kid]; ok {
+		return key, nil
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := fetchJSON(ctx, discovery.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]interface{}{}
+	for _, k := range set.Keys {
+		switch {
+		case k.Kty == "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case k.Kty == "EC" && k.Crv == "P-256":
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	oidcCache.keys = keys
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider key %q", kid)
+	}
+	return key, nil
+}
+
+func fetchJSON(ctx context.Context, target string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := oidcHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %d", target, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func intersect(a, b []string) []string {
+	var out []string
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				out = append(out, x)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// ResolveOIDCUser - Finds the account linked to the ID token subject, links an
+// existing account with the same verified email, or provisions a new member
+// when OIDC_AUTO_PROVISION is enabled
+func ResolveOIDCUser(ctx context.Context, cfg *OIDCConfig, claims *IDTokenClaims) (*models.User, error) {
+	claims.Email = utils.NormalizeEmail(claims.Email)
+
+	// Accounts are matched and linked by email, which is only safe when the
+	// provider vouches for the address
+	if claims.Email == "" || !claims.IsEmailVerified() {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	var user models.User
+	err := config.UsersCollection.FindOne(ctx, bson.M{"oidc_issuer": cfg.Issuer, "oidc_subject": claims.Subject}).Decode(&user)
+	if err == nil {
+		return &user, nil
+	} else if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	now := time.Now()
+	err = config.UsersCollection.FindOneAndUpdate(ctx,
+		bson.M{"email": claims.Email, "oidc_subject": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{
+			"oidc_issuer":    cfg.Issuer,
+			"oidc_subject":   claims.Subject,
+			"email_verified": true,
+			"updated_at":     now,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err == nil {
+		return &user, nil
+	} else if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	// An account with this email that is linked to another identity is left alone
+	count, err := config.UsersCollection.CountDocuments(ctx, bson.M{"email": claims.Email})
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrOIDCAccountLinked
+	}
+
+	if !config.GetEnvBool("OIDC_AUTO_PROVISION", false) {
+		return nil, ErrOIDCUserNotFound
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = strings.Split(claims.Email, "@")[0]
+	}
+
+	// Provisioned users have no password and can only sign in through SSO
+	user = models.User{
+		ID:              primitive.NewObjectID(),
+		Name:            name,
+		Email:           claims.Email,
+		Role:            models.RoleMember,
+		EmailVerified:   true,
+		EmailVerifiedAt: &now,
+		OIDCIssuer:      cfg.Issuer,
+		OIDCSubject:     claims.Subject,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if _, err := config.UsersCollection.InsertOne(ctx, user); err != nil {
+		return nil, err
+	}
+	if _, err := EnsurePersonalWorkspace(ctx, &user); err != nil {
+		// Leave no account behind that couldn't use tasks; the next sign-in retries
+		config.UsersCollection.DeleteOne(ctx, bson.M{"_id": user.ID})
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	mockClientID = "task-manager"
+	mockKeyID    = "mock-key"
+	mockNonce    = "expected-nonce"
+	mockVerifier = "code-verifier"
+)
+
+// mockIdP is a local OpenID provider serving discovery, JWKS and a token
+// endpoint that answers with whatever ID token the test configured
+type mockIdP struct {
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	idToken string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                idp.server.URL,
+			"authorization_endpoint":                idp.server.URL + "/authorize",
+			"token_endpoint":                        idp.server.URL + "/token",
+			"jwks_uri":                              idp.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": mockKeyID,
+				"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" ||
+			r.PostForm.Get("code") != "good-code" || r.PostForm.Get("code_verifier") != mockVerifier {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idp.idToken})
+	})
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *mockIdP) config() *OIDCConfig {
+	return &OIDCConfig{Issuer: idp.server.URL, ClientID: mockClientID, RedirectURL: "http://localhost/auth/oidc/callback"}
+}
+
+// claims returns a valid set of ID token claims for the mock provider
+func (idp *mockIdP) claims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"sub":            "subject-1",
+		"aud":            mockClientID,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          mockNonce,
+		"email":          "sso@example.com",
+		"email_verified": true,
+		"name":           "SSO User",
+	}
+}
+
+func (idp *mockIdP) sign(t *testing.T, claims jwt.MapClaims, key *rsa.PrivateKey) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = mockKeyID
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestExchangeOIDCCodeAcceptsValidIDToken(t *testing.T) {
+	idp := newMockIdP(t)
+	idp.idToken = idp.sign(t, idp.claims(), idp.key)
+
+	claims, err := ExchangeOIDCCode(context.Background(), idp.config(), "good-code", mockVerifier, mockNonce)
+	if err != nil {
+		t.Fatalf("ExchangeOIDCCode: %v", err)
+	}
+	if claims.Subject != "subject-1" || claims.Email != "sso@example.com" || !claims.IsEmailVerified() {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+}
+
+func TestExchangeOIDCCodeRejectsInvalidIDTokens(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(jwt.MapClaims)
+		key    *rsa.PrivateKey
+		nonce  string
+	}{
+		{name: "bad nonce", nonce: "other-nonce"},
+		{name: "missing nonce claim", mutate: func(c jwt.MapClaims) { delete(c, "nonce") }},
+		{name: "bad audience", mutate: func(c jwt.MapClaims) { c["aud"] = "someone-else" }},
+		{name: "bad issuer", mutate: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{name: "expired", mutate: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{name: "bad signature", key: otherKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newMockIdP(t)
+			claims := idp.claims()
+			if tt.mutate != nil {
+				tt.mutate(claims)
+			}
+			key := idp.key
+			if tt.key != nil {
+				key = tt.key
+			}
+			nonce := mockNonce
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+			idp.idToken = idp.sign(t, claims, key)
+
+			if _, err := ExchangeOIDCCode(context.Background(), idp.config(), "good-code", mockVerifier, nonce); err != ErrOIDCInvalidToken {
+				t.Fatalf("got %v, want ErrOIDCInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestExchangeOIDCCodeRejectsWrongCodeVerifier(t *testing.T) {
+	idp := newMockIdP(t)
+	idp.idToken = idp.sign(t, idp.claims(), idp.key)
+
+	if _, err := ExchangeOIDCCode(context.Background(), idp.config(), "good-code", "wrong-verifier", mockNonce); err == nil {
+		t.Fatal("expected the token endpoint to refuse the exchange")
+	}
+}
+
+func TestOIDCEmailVerified(t *testing.T) {
+	tests := []struct {
+		name     string
+		verified interface{}
+		want     bool
+	}{
+		{name: "bool true", verified: true, want: true},
+		{name: "string true", verified: "true", want: true},
+		{name: "bool false", verified: false, want: false},
+		{name: "string false", verified: "false", want: false},
+		{name: "missing", verified: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newMockIdP(t)
+			claims := idp.claims()
+			if tt.verified == nil {
+				delete(claims, "email_verified")
+			} else {
+				claims["email_verified"] = tt.verified
+			}
+			idp.idToken = idp.sign(t, claims, idp.key)
+
+			got, err := ExchangeOIDCCode(context.Background(), idp.config(), "good-code", mockVerifier, mockNonce)
+			if err != nil {
+				t.Fatalf("ExchangeOIDCCode: %v", err)
+			}
+			if got.IsEmailVerified() != tt.want {
+				t.Fatalf("IsEmailVerified() = %v, want %v", got.IsEmailVerified(), tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveOIDCUserRejectsUnverifiedEmail(t *testing.T) {
+	idp := newMockIdP(t)
+	claims := idp.claims()
+	claims["email_verified"] = false
+	idp.idToken = idp.sign(t, claims, idp.key)
+
+	exchanged, err := ExchangeOIDCCode(context.Background(), idp.config(), "good-code", mockVerifier, mockNonce)
+	if err != nil {
+		t.Fatalf("ExchangeOIDCCode: %v", err)
+	}
+
+	// Rejected before any account lookup, so no database is needed
+	if _, err := ResolveOIDCUser(context.Background(), idp.config(), exchanged); err != ErrOIDCEmailNotVerified {
+		t.Fatalf("got %v, want ErrOIDCEmailNotVerified", err)
+	}
+}
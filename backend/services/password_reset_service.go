@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+)
+
+// PasswordResetTTL is how long an emailed reset link stays valid
+const PasswordResetTTL = time.Hour
+
+var ErrPasswordResetInvalid = errors.New("invalid or expired password reset token")
+
+// StartPasswordReset - Replaces any outstanding reset for the user with a new
+// one and emails the link
+func StartPasswordReset(ctx context.Context, user *models.User, ip string) error {
+	raw, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	// Only the most recent link should work
+	if _, err := config.PasswordResetsCollection.DeleteMany(ctx, bson.M{"user_id": user.ID, "used_at": bson.M{"$exists": false}}); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err = config.PasswordResetsCollection.InsertOne(ctx, models.PasswordReset{
+		ID:        primitive.NewObjectID(),
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(raw),
+		RequestIP: ip,
+		ExpiresAt: now.Add(PasswordResetTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", config.FrontendURL(), url.QueryEscape(raw))
+	body := fmt.Sprintf("Hi %s,\n\nWe received a request to reset your password. Open the link below to choose a new one:\n\n%s\n\nThe link expires in %s. If you didn't ask for this, you can ignore this email.\n",
+		user.Name, link, PasswordResetTTL)
+
+	return SendMail(user.Email, "Reset your password", body)
+}
+
+// FindPasswordReset - Returns the user of a pending reset token without using
+// it up, so the new password can be checked before the token is consumed
+func FindPasswordReset(ctx context.Context, raw string) (primitive.ObjectID, error) {
+	var reset models.PasswordReset
+	err := config.PasswordResetsCollection.FindOne(ctx, pendingResetFilter(raw)).Decode(&reset)
+	if err == mongo.ErrNoDocuments {
+		return primitive.NilObjectID, ErrPasswordResetInvalid
+	} else if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return reset.UserID, nil
+}
+
+// ConsumePasswordReset - Marks a reset token as used and returns its user.
+// The token is claimed atomically so it can only ever be used once.
+func ConsumePasswordReset(ctx context.Context, raw string) (primitive.ObjectID, error) {
+	var reset models.PasswordReset
+	err := config.PasswordResetsCollection.FindOneAndUpdate(ctx,
+		pendingResetFilter(raw),
+		bson.M{"$set": bson.M{"used_at": time.Now()}},
+	).Decode(&reset)
+	if err == mongo.ErrNoDocuments {
+		return primitive.NilObjectID, ErrPasswordResetInvalid
+	} else if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return reset.UserID, nil
+}
+
+func pendingResetFilter(raw string) bson.M {
+	return bson.M{
+		"token_hash": utils.HashToken(raw),
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+}
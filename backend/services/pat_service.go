@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+)
+
+// PersonalAccessTokenPrefix marks bearer tokens that are PATs rather than JWTs
+const PersonalAccessTokenPrefix = "pat_"
+
+var ErrPersonalAccessTokenInvalid = errors.New("invalid, expired or revoked personal access token")
+
+// IsPersonalAccessToken - Reports whether a bearer token is a PAT
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+// CreatePersonalAccessToken - Stores a new PAT and returns its secret, which is never retrievable again
+func CreatePersonalAccessToken(ctx context.Context, userID primitive.ObjectID, name string, scopes []string, expiresAt time.Time) (string, *models.PersonalAccessToken, error) {
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	raw := PersonalAccessTokenPrefix + secret
+
+	token := &models.PersonalAccessToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Name:      name,
+		Scopes:    scopes,
+		Prefix:    raw[:len(PersonalAccessTokenPrefix)+6],
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+	if _, err := config.PersonalAccessTokensCollection.InsertOne(ctx, token); err != nil {
+		return "", nil, err
+	}
+
+	return raw, token, nil
+}
+
+// ListPersonalAccessTokens - Returns the user's tokens, newest first
+func ListPersonalAccessTokens(ctx context.Context, userID primitive.ObjectID) ([]models.PersonalAccessToken, error) {
+	cursor, err := config.PersonalAccessTokensCollection.Find(ctx,
+		bson.M{"user_id": userID},
+		options.Find().SetSort(bson.M{"created_at": -1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tokens := []models.PersonalAccessToken{}
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RevokePersonalAccessToken - Revokes one of the user's tokens; false if it doesn't exist
+func RevokePersonalAccessToken(ctx context.Context, userID, tokenID primitive.ObjectID) (bool, error) {
+	result, err := config.PersonalAccessTokensCollection.UpdateOne(ctx,
+		bson.M{"_id": tokenID, "user_id": userID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// AuthenticatePersonalAccessToken - Looks up an active PAT and records when and from where it was used
+func AuthenticatePersonalAccessToken(ctx context.Context, raw, ip string) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	err := config.PersonalAccessTokensCollection.FindOneAndUpdate(ctx,
+		bson.M{
+			"token_hash": utils.HashToken(raw),
+			"revoked_at": bson.M{"$exists": false},
+			"expires_at": bson.M{"$gt": time.Now()},
+		},
+		bson.M{"$set": bson.M{"last_used_at": time.Now(), "last_used_ip": ip}},
+	).Decode(&token)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrPersonalAccessTokenInvalid
+	} else if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RevokeUserPersonalAccessTokens - Revokes every token belonging to the user
+func RevokeUserPersonalAccessTokens(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := config.PersonalAccessTokensCollection.UpdateMany(ctx,
+		bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	return err
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+)
+
+// ServiceAccountKeyPrefix marks bearer tokens that are service account API keys
+const ServiceAccountKeyPrefix = "tsa_"
+
+var (
+	ErrServiceAccountNotFound   = errors.New("service account not found")
+	ErrServiceAccountKeyInvalid = errors.New("invalid, expired or revoked service account key")
+)
+
+// IsServiceAccountKey - Reports whether a bearer token is a service account key
+func IsServiceAccountKey(token string) bool {
+	return strings.HasPrefix(token, ServiceAccountKeyPrefix)
+}
+
+// CreateServiceAccount - Stores a new service account
+func CreateServiceAccount(ctx context.Context, account *models.ServiceAccount) error {
+	now := time.Now()
+	account.ID = primitive.NewObjectID()
+	account.CreatedAt = now
+	account.UpdatedAt = now
+
+	_, err := config.ServiceAccountsCollection.InsertOne(ctx, account)
+	return err
+}
+
+// ListServiceAccounts - Returns the workspace's service accounts, oldest first
+func ListServiceAccounts(ctx context.Context, workspaceID primitive.ObjectID) ([]models.ServiceAccount, error) {
+	cursor, err := config.ServiceAccountsCollection.Find(ctx,
+		bson.M{"workspace_id": workspaceID},
+		options.Find().SetSort(bson.M{"created_at": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := []models.ServiceAccount{}
+	if err := cursor.All(ctx, &accounts); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// GetServiceAccount - Loads a service account of the workspace
+func GetServiceAccount(ctx context.Context, workspaceID, accountID primitive.ObjectID) (*models.ServiceAccount, error) {
+	var account models.ServiceAccount
+	err := config.ServiceAccountsCollection.FindOne(ctx, bson.M{"_id": accountID, "workspace_id": workspaceID}).Decode(&account)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrServiceAccountNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// UpdateServiceAccount - Applies changes to a service account of the workspace
+func UpdateServiceAccount(ctx context.Context, workspaceID, accountID primitive.ObjectID, changes bson.M) error {
+	changes["updated_at"] = time.Now()
+
+	result, err := config.ServiceAccountsCollection.UpdateOne(ctx,
+		bson.M{"_id": accountID, "workspace_id": workspaceID},
+		bson.M{"$set": changes},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrServiceAccountNotFound
+	}
+	return nil
+}
+
+// DeleteServiceAccount - Deletes a service account of the workspace with its
+// keys. Tasks and comments it wrote keep its ID and actor type.
+func DeleteServiceAccount(ctx context.Context, workspaceID, accountID primitive.ObjectID) error {
+	result, err := config.ServiceAccountsCollection.DeleteOne(ctx, bson.M{"_id": accountID, "workspace_id": workspaceID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrServiceAccountNotFound
+	}
+
+	_, err = config.ServiceAccountKeysCollection.DeleteMany(ctx, bson.M{"service_account_id": accountID})
+	return err
+}
+
+// DeleteWorkspaceServiceAccounts - Deletes every service account of the
+// workspace with their keys
+func DeleteWorkspaceServiceAccounts(ctx context.Context, workspaceID primitive.ObjectID) error {
+	accounts, err := ListServiceAccounts(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(accounts))
+	for _, account := range accounts {
+		ids = append(ids, account.ID)
+	}
+	if _, err := config.ServiceAccountKeysCollection.DeleteMany(ctx, bson.M{"service_account_id": bson.M{"$in": ids}}); err != nil {
+		return err
+	}
+
+	_, err = config.ServiceAccountsCollection.DeleteMany(ctx, bson.M{"workspace_id": workspaceID})
+	return err
+}
+
+// CreateServiceAccountKey - Stores a new API key and returns its secret, which is never retrievable again
+func CreateServiceAccountKey(ctx context.Context, accountID primitive.ObjectID, name string, expiresAt time.Time) (string, *models.ServiceAccountKey, error) {
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	raw := ServiceAccountKeyPrefix + secret
+
+	key := &models.ServiceAccountKey{
+		ID:               primitive.NewObjectID(),
+		ServiceAccountID: accountID,
+		Name:             name,
+		Prefix:           raw[:len(ServiceAccountKeyPrefix)+6],
+		TokenHash:        utils.HashToken(raw),
+		ExpiresAt:        expiresAt,
+		CreatedAt:        time.Now(),
+	}
+	if _, err := config.ServiceAccountKeysCollection.InsertOne(ctx, key); err != nil {
+		return "", nil, err
+	}
+
+	return raw, key, nil
+}
+
+// ListServiceAccountKeys - Returns the service account's keys, newest first
+func ListServiceAccountKeys(ctx context.Context, accountID primitive.ObjectID) ([]models.ServiceAccountKey, error) {
+	cursor, err := config.ServiceAccountKeysCollection.Find(ctx,
+		bson.M{"service_account_id": accountID},
+		options.Find().SetSort(bson.M{"created_at": -1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []models.ServiceAccountKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeServiceAccountKey - Revokes one of the service account's keys; false if it doesn't exist
+func RevokeServiceAccountKey(ctx context.Context, accountID, keyID primitive.ObjectID) (bool, error) {
+	result, err := config.ServiceAccountKeysCollection.UpdateOne(ctx,
+		bson.M{"_id": keyID, "service_account_id": accountID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// AuthenticateServiceAccountKey - Looks up an active key, records when and
+// from where it was used and returns its service account, which must not be
+// disabled
+func AuthenticateServiceAccountKey(ctx context.Context, raw, ip string) (*models.ServiceAccount, error) {
+	var key models.ServiceAccountKey
+	err := config.ServiceAccountKeysCollection.FindOneAndUpdate(ctx,
+		bson.M{
+			"token_hash": utils.HashToken(raw),
+			"revoked_at": bson.M{"$exists": false},
+			"expires_at": bson.M{"$gt": time.Now()},
+		},
+		bson.M{"$set": bson.M{"last_used_at": time.Now(), "last_used_ip": ip}},
+	).Decode(&key)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrServiceAccountKeyInvalid
+	} else if err != nil {
+		return nil, err
+	}
+
+	var account models.ServiceAccount
+	err = config.ServiceAccountsCollection.FindOne(ctx, bson.M{"_id": key.ServiceAccountID, "disabled": bson.M{"$ne": true}}).Decode(&account)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrServiceAccountKeyInvalid
+	} else if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
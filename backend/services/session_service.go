@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+)
+
+// sessionTouchInterval limits last-seen writes to one per session per interval
+const sessionTouchInterval = time.Minute
+
+var (
+	sessionTouchMu   sync.Mutex
+	sessionTouchedAt = map[string]time.Time{}
+)
+
+// CreateSession - Records a new login for the refresh token family sessionID
+func CreateSession(ctx context.Context, userID, sessionID primitive.ObjectID, jti, userAgent, ip string) (*models.Session, error) {
+	now := time.Now()
+	session := &models.Session{
+		ID:         sessionID,
+		UserID:     userID,
+		CurrentJTI: jti,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(utils.RefreshTokenTTL),
+	}
+	if _, err := config.SessionsCollection.InsertOne(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// RenewSession - Points the session at a freshly issued access token after a refresh
+func RenewSession(ctx context.Context, sessionID primitive.ObjectID, jti, ip string) error {
+	now := time.Now()
+	_, err := config.SessionsCollection.UpdateOne(ctx,
+		bson.M{"_id": sessionID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{
+			"current_jti":  jti,
+			"last_seen_at": now,
+			"last_seen_ip": ip,
+			"expires_at":   now.Add(utils.RefreshTokenTTL),
+		}},
+	)
+	return err
+}
+
+// TouchSession - Updates last-seen in the background, at most once per
+// sessionTouchInterval per session so authenticated requests stay cheap
+func TouchSession(sessionID, ip string) {
+	id, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	sessionTouchMu.Lock()
+	if now.Sub(sessionTouchedAt[sessionID]) < sessionTouchInterval {
+		sessionTouchMu.Unlock()
+		return
+	}
+	sessionTouchedAt[sessionID] = now
+	// Drop stale entries so the map doesn't grow with every session ever seen
+	if len(sessionTouchedAt) > 10000 {
+		for k, t := range sessionTouchedAt {
+			if now.Sub(t) >= sessionTouchInterval {
+				delete(sessionTouchedAt, k)
+			}
+		}
+	}
+	sessionTouchMu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_, err := config.SessionsCollection.UpdateOne(ctx,
+			bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"last_seen_at": now, "last_seen_ip": ip}},
+		)
+		if err != nil {
+			log.Println("Failed to update session last seen:", err)
+		}
+	}()
+}
+
+// ListSessions - Returns the user's active sessions, most recently used first
+func ListSessions(ctx context.Context, userID primitive.ObjectID) ([]models.Session, error) {
+	cursor, err := config.SessionsCollection.Find(ctx,
+		bson.M{
+			"user_id":    userID,
+			"revoked_at": bson.M{"$exists": false},
+			"expires_at": bson.M{"$gt": time.Now()},
+		},
+		options.Find().SetSort(bson.M{"last_seen_at": -1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []models.Session{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// SessionAuthenticatedSince - Reports whether the user's active session was
+// started by a login at or after since. Refreshing keeps a session, so its
+// creation time is when the user last proved who they are.
+func SessionAuthenticatedSince(ctx context.Context, userID, sessionID primitive.ObjectID, since time.Time) (bool, error) {
+	count, err := config.SessionsCollection.CountDocuments(ctx, bson.M{
+		"_id":        sessionID,
+		"user_id":    userID,
+		"revoked_at": bson.M{"$exists": false},
+		"created_at": bson.M{"$gte": since},
+	})
+	if err != nil {
+		return false, err
+	}
+	return count == 1, nil
+}
+
+// RevokeSession - Signs one of the user's sessions out: its refresh tokens are
+// revoked and every access token issued for it is denylisted. Returns false if
+// the user has no such active session.
+func RevokeSession(ctx context.Context, userID, sessionID primitive.ObjectID) (bool, error) {
+	count, err := config.SessionsCollection.CountDocuments(ctx, bson.M{
+		"_id":        sessionID,
+		"user_id":    userID,
+		"revoked_at": bson.M{"$exists": false},
+	})
+	if err != nil || count == 0 {
+		return false, err
+	}
+
+	return true, RevokeTokenFamily(ctx, userID, sessionID)
+}
+
+// RevokeOtherSessions - Signs out every session of the user except keep
+func RevokeOtherSessions(ctx context.Context, userID, keep primitive.ObjectID) error {
+	sessions, err := ListSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sessions {
+		if s.ID == keep {
+			continue
+		}
+		if _, err := RevokeSession(ctx, userID, s.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// IssueRefreshToken - Stores a new refresh token in the given family and returns the raw value
+func IssueRefreshToken(ctx context.Context, userID, familyID primitive.ObjectID) (string, *models.RefreshToken, error) {
+	raw, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	token := &models.RefreshToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: now.Add(utils.RefreshTokenTTL),
+		CreatedAt: now,
+	}
+
+	if _, err := config.RefreshTokensCollection.InsertOne(ctx, token); err != nil {
+		return "", nil, err
+	}
+
+	return raw, token, nil
+}
+
+// RotateRefreshToken - Exchanges a refresh token for a new one in the same family.
+// Presenting a token that was already rotated or revoked revokes the whole family.
+func RotateRefreshToken(ctx context.Context, raw string) (string, *models.RefreshToken, error) {
+	hash := utils.HashToken(raw)
+	newID := primitive.NewObjectID()
+
+	// Claim the token atomically so two concurrent refreshes can't both succeed
+	var current models.RefreshToken
+	err := config.RefreshTokensCollection.FindOneAndUpdate(ctx,
+		bson.M{
+			"token_hash":  hash,
+			"replaced_by": bson.M{"$exists": false},
+			"revoked_at":  bson.M{"$exists": false},
+			"expires_at":  bson.M{"$gt": time.Now()},
+		},
+		bson.M{"$set": bson.M{"replaced_by": newID}},
+	).Decode(&current)
+
+	if err == mongo.ErrNoDocuments {
+		var existing models.RefreshToken
+		err = config.RefreshTokensCollection.FindOne(ctx, bson.M{"token_hash": hash}).Decode(&existing)
+		if err == mongo.ErrNoDocuments {
+			return "", nil, ErrRefreshTokenInvalid
+		} else if err != nil {
+			return "", nil, err
+		}
+
+		if existing.ReplacedBy != nil || existing.RevokedAt != nil {
+			if err := RevokeTokenFamily(ctx, existing.UserID, existing.FamilyID); err != nil {
+				return "", nil, err
+			}
+			return "", nil, ErrRefreshTokenReused
+		}
+		return "", nil, ErrRefreshTokenInvalid
+	} else if err != nil {
+		return "", nil, err
+	}
+
+	newRaw, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	next := &models.RefreshToken{
+		ID:        newID,
+		UserID:    current.UserID,
+		FamilyID:  current.FamilyID,
+		TokenHash: utils.HashToken(newRaw),
+		ExpiresAt: now.Add(utils.RefreshTokenTTL),
+		CreatedAt: now,
+	}
+
+	if _, err := config.RefreshTokensCollection.InsertOne(ctx, next); err != nil {
+		return "", nil, err
+	}
+
+	return newRaw, next, nil
+}
+
+// RevokeTokenFamily - Revokes every refresh token issued for one login, ends
+// its session and denylists every access token issued in it
+func RevokeTokenFamily(ctx context.Context, userID, familyID primitive.ObjectID) error {
+	now := time.Now()
+	_, err := config.RevokedTokensCollection.InsertOne(ctx, models.RevokedToken{
+		ID:        primitive.NewObjectID(),
+		SessionID: familyID.Hex(),
+		UserID:    userID,
+		ExpiresAt: now.Add(utils.AccessTokenTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = config.RefreshTokensCollection.UpdateMany(ctx,
+		bson.M{"family_id": familyID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": now}},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = config.SessionsCollection.UpdateOne(ctx,
+		bson.M{"_id": familyID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": now}},
+	)
+	return err
+}
+
+// RevokeUserRefreshTokens - Revokes every refresh token belonging to the user
+// and ends all of their sessions
+func RevokeUserRefreshTokens(ctx context.Context, userID primitive.ObjectID) error {
+	now := time.Now()
+	_, err := config.RefreshTokensCollection.UpdateMany(ctx,
+		bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": now}},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = config.SessionsCollection.UpdateMany(ctx,
+		bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": now}},
+	)
+	return err
+}
+
+// RevokeAccessToken - Denylists a single access token until it would have expired
+func RevokeAccessToken(ctx context.Context, claims *utils.Claims) error {
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(utils.AccessTokenTTL)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	_, err = config.RevokedTokensCollection.InsertOne(ctx, models.RevokedToken{
+		ID:        primitive.NewObjectID(),
+		JTI:       claims.ID,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	})
+	return err
+}
+
+// RevokeAllUserTokens - Signs the user out everywhere: every access token issued
+// so far is denylisted and every refresh token family is revoked
+func RevokeAllUserTokens(ctx context.Context, userID primitive.ObjectID) error {
+	// iat has second precision, so cut off at the start of the current second
+	now := time.Now().Truncate(time.Second)
+	_, err := config.RevokedTokensCollection.InsertOne(ctx, models.RevokedToken{
+		ID:           primitive.NewObjectID(),
+		UserID:       userID,
+		IssuedBefore: &now,
+		ExpiresAt:    now.Add(utils.AccessTokenTTL + time.Second),
+		CreatedAt:    now,
+	})
+	if err != nil {
+		return err
+	}
+
+	return RevokeUserRefreshTokens(ctx, userID)
+}
+
+// IsAccessTokenRevoked - Reports whether the token has been denylisted, either
+// individually, through its session, or by a sign-out-everywhere entry for its user
+func IsAccessTokenRevoked(ctx context.Context, claims *utils.Claims) (bool, error) {
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	conditions := []bson.M{{"jti": claims.ID}}
+	if claims.SessionID != "" {
+		conditions = append(conditions, bson.M{"session_id": claims.SessionID})
+	}
+	if claims.IssuedAt != nil {
+		conditions = append(conditions, bson.M{"user_id": userID, "issued_before": bson.M{"$gt": claims.IssuedAt.Time}})
+	}
+
+	count, err := config.RevokedTokensCollection.CountDocuments(ctx, bson.M{"$or": conditions}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestRevokeTokenFamilyDenylistsSession(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("family", func(mt *mtest.T) {
+		useMockCollections(mt)
+
+		userID, familyID := primitive.NewObjectID(), primitive.NewObjectID()
+		updated := bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}}
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), updated, updated)
+
+		if err := RevokeTokenFamily(context.Background(), userID, familyID); err != nil {
+			mt.Fatalf("RevokeTokenFamily: %v", err)
+		}
+
+		insert := startedCommand(mt, "insert")
+		if collection := insert.Lookup("insert").StringValue(); collection != "revoked_tokens" {
+			mt.Fatalf("inserted into %q, want revoked_tokens", collection)
+		}
+		entry := insert.Lookup("documents", "0").Document()
+		if entry.Lookup("session_id").StringValue() != familyID.Hex() || entry.Lookup("user_id").ObjectID() != userID {
+			mt.Fatalf("denylist entry %v does not cover the session", entry)
+		}
+	})
+}
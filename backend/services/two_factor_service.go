@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+)
+
+const (
+	// RecoveryCodeCount is how many one-time recovery codes are issued at enrolment
+	RecoveryCodeCount = 10
+	// TwoFactorChallengeTTL bounds the gap between the password and the second factor
+	TwoFactorChallengeTTL = 5 * time.Minute
+)
+
+// TwoFactorIssuer is the account label shown in authenticator apps
+func TwoFactorIssuer() string {
+	return config.GetEnv("TOTP_ISSUER", "AI Task Manager")
+}
+
+// VerifyTOTPCode - Checks a TOTP code for a user with 2FA enabled at time now.
+// The matched step is recorded atomically so each code works only once.
+func VerifyTOTPCode(ctx context.Context, user *models.User, code string, now time.Time) (bool, error) {
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, code, now)
+	if !ok || step <= user.TOTPLastStep {
+		return false, nil
+	}
+
+	result, err := config.UsersCollection.UpdateOne(ctx,
+		bson.M{"_id": user.ID, "$or": []bson.M{
+			{"totp_last_step": bson.M{"$exists": false}},
+			{"totp_last_step": bson.M{"$lt": step}},
+		}},
+		bson.M{"$set": bson.M{"totp_last_step": step}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// ConsumeRecoveryCode - Removes a matching recovery code so it can't be reused
+func ConsumeRecoveryCode(ctx context.Context, user *models.User, code string) (bool, error) {
+	hash, remaining, ok := redeemRecoveryCode(user.RecoveryCodes, code)
+	if !ok {
+		return false, nil
+	}
+
+	// The filter makes the removal atomic, so a code raced twice works once
+	result, err := config.UsersCollection.UpdateOne(ctx,
+		bson.M{"_id": user.ID, "recovery_codes": hash},
+		bson.M{"$pull": bson.M{"recovery_codes": hash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	if result.ModifiedCount != 1 {
+		return false, nil
+	}
+
+	user.RecoveryCodes = remaining
+	return true, nil
+}
+
+// redeemRecoveryCode finds code among the stored hashes and returns its hash
+// with the hashes that remain once it is used
+func redeemRecoveryCode(hashes []string, code string) (string, []string, bool) {
+	hash := utils.HashToken(utils.NormalizeRecoveryCode(code))
+	for i, stored := range hashes {
+		if stored == hash {
+			remaining := append(append([]string{}, hashes[:i]...), hashes[i+1:]...)
+			return hash, remaining, true
+		}
+	}
+	return "", hashes, false
+}
+
+// VerifySecondFactor - Accepts either a TOTP code or a recovery code
+func VerifySecondFactor(ctx context.Context, user *models.User, code, recoveryCode string, now time.Time) (bool, error) {
+	if code != "" {
+		return VerifyTOTPCode(ctx, user, code, now)
+	}
+	if recoveryCode != "" {
+		return ConsumeRecoveryCode(ctx, user, recoveryCode)
+	}
+	return false, nil
+}
+
+// HashRecoveryCodes - Hashes freshly generated recovery codes for storage
+func HashRecoveryCodes(codes []string) []string {
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashToken(utils.NormalizeRecoveryCode(code))
+	}
+	return hashes
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"backend/models"
+	"backend/utils"
+)
+
+var testTOTPSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestVerifyTOTPCodeRejectsReplayedStep(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := utils.TOTPStep(now)
+	code, err := utils.TOTPCode(testTOTPSecret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Once a step has been used, neither it nor an earlier one in the skew
+	// window is accepted again. Rejection happens before any database write.
+	for _, last := range []int64{step, step + 1} {
+		user := &models.User{TOTPSecret: testTOTPSecret, TOTPLastStep: last}
+		ok, err := VerifyTOTPCode(context.Background(), user, code, now)
+		if err != nil {
+			t.Fatalf("VerifyTOTPCode: %v", err)
+		}
+		if ok {
+			t.Fatalf("code for step %d accepted after step %d was used", step, last)
+		}
+	}
+}
+
+func TestVerifyTOTPCodeRejectsWrongCode(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	user := &models.User{TOTPSecret: testTOTPSecret}
+
+	ok, err := VerifyTOTPCode(context.Background(), user, "000000", now)
+	if err != nil || ok {
+		t.Fatalf("VerifyTOTPCode = %v, %v; want false, nil", ok, err)
+	}
+}
+
+func TestRecoveryCodesAreSingleUse(t *testing.T) {
+	codes := []string{"aaaaa-bbbbb", "ccccc-ddddd", "eeeee-fffff"}
+	hashes := HashRecoveryCodes(codes)
+
+	hash, remaining, ok := redeemRecoveryCode(hashes, "CCCCC-DDDDD")
+	if !ok || hash != hashes[1] {
+		t.Fatal("valid recovery code was not accepted")
+	}
+	if len(remaining) != 2 || remaining[0] != hashes[0] || remaining[1] != hashes[2] {
+		t.Fatalf("unexpected remaining codes: %v", remaining)
+	}
+	if len(hashes) != 3 {
+		t.Fatal("redeeming modified the stored hashes")
+	}
+
+	if _, _, ok := redeemRecoveryCode(remaining, "ccccc-ddddd"); ok {
+		t.Fatal("recovery code accepted twice")
+	}
+}
+
+func TestConsumeRecoveryCodeRejectsUnknownCode(t *testing.T) {
+	user := &models.User{RecoveryCodes: HashRecoveryCodes([]string{"aaaaa-bbbbb"})}
+
+	// Unknown codes are rejected without touching the database
+	ok, err := ConsumeRecoveryCode(context.Background(), user, "zzzzz-zzzzz")
+	if err != nil || ok {
+		t.Fatalf("ConsumeRecoveryCode = %v, %v; want false, nil", ok, err)
+	}
+}
+
+func TestVerifySecondFactorNeedsACode(t *testing.T) {
+	ok, err := VerifySecondFactor(context.Background(), &models.User{TOTPSecret: testTOTPSecret}, "", "", time.Now())
+	if err != nil || ok {
+		t.Fatalf("VerifySecondFactor = %v, %v; want false, nil", ok, err)
+	}
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+)
+
+var ErrLastAdmin = errors.New("at least one admin must remain")
+
+// UserFilter narrows ListUsers; zero values match everything
+type UserFilter struct {
+	Query    string // Case-insensitive substring of name or email
+	Role     models.Role
+	Disabled *bool
+}
+
+// GetUserAccess - Loads only the fields AuthMiddleware needs on every request
+func GetUserAccess(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	var user models.User
+	err := config.UsersCollection.FindOne(ctx,
+		bson.M{"_id": userID},
+		options.FindOne().SetProjection(bson.M{"role": 1, "disabled": 1, "deletion_scheduled_for": 1}),
+	).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListUsers - Returns one page of users matching the filter, newest first,
+// along with the total number of matches
+func ListUsers(ctx context.Context, filter UserFilter, page, perPage int) ([]models.User, int64, error) {
+	query := bson.M{}
+	if filter.Query != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(filter.Query), Options: "i"}
+		query["$or"] = []bson.M{{"name": pattern}, {"email": pattern}}
+	}
+	if filter.Role != "" {
+		if filter.Role == models.RoleMember {
+			// Accounts created before roles existed have no role field
+			query["role"] = bson.M{"$in": []interface{}{models.RoleMember, nil}}
+		} else {
+			query["role"] = filter.Role
+		}
+	}
+	if filter.Disabled != nil {
+		if *filter.Disabled {
+			query["disabled"] = true
+		} else {
+			query["disabled"] = bson.M{"$ne": true}
+		}
+	}
+
+	total, err := config.UsersCollection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cursor, err := config.UsersCollection.Find(ctx, query, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((page-1)*perPage)).
+		SetLimit(int64(perPage)),
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	users := []models.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// SetUserDisabled - Disables or re-enables an account. Disabling also signs
+// the user out everywhere, so re-enabling requires a fresh login.
+func SetUserDisabled(ctx context.Context, userID primitive.ObjectID, disabled bool) error {
+	update := bson.M{
+		"$set": bson.M{"disabled": true, "disabled_at": time.Now(), "updated_at": time.Now()},
+	}
+	if !disabled {
+		update = bson.M{
+			"$set":   bson.M{"updated_at": time.Now()},
+			"$unset": bson.M{"disabled": "", "disabled_at": ""},
+		}
+	}
+
+	result, err := config.UsersCollection.UpdateOne(ctx, bson.M{"_id": userID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	if disabled {
+		return RevokeAllUserTokens(ctx, userID)
+	}
+	return nil
+}
+
+// ResetTwoFactor - Turns off 2FA and discards the secret and recovery codes,
+// for users who lost their authenticator
+func ResetTwoFactor(ctx context.Context, userID primitive.ObjectID) error {
+	result, err := config.UsersCollection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{
+			"$set":   bson.M{"two_factor_enabled": false, "updated_at": time.Now()},
+			"$unset": bson.M{"totp_secret": "", "totp_pending_secret": "", "totp_last_step": "", "recovery_codes": ""},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// SetUserRole - Changes a user's global role, refusing to demote the last admin
+func SetUserRole(ctx context.Context, userID primitive.ObjectID, role models.Role) error {
+	if role != models.RoleAdmin {
+		count, err := config.UsersCollection.CountDocuments(ctx, bson.M{
+			"role":     models.RoleAdmin,
+			"disabled": bson.M{"$ne": true},
+			"_id":      bson.M{"$ne": userID},
+		})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrLastAdmin
+		}
+	}
+
+	result, err := config.UsersCollection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"role": role, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// BootstrapAdmins - Promotes the accounts listed in ADMIN_EMAILS (comma
+// separated) to global admin, so the first admin doesn't have to be made by
+// editing the database. Only verified addresses are promoted, so registering
+// a listed address isn't enough on its own. Safe to run on every start.
+func BootstrapAdmins(ctx context.Context) error {
+	emails := parseAdminEmails(config.GetEnv("ADMIN_EMAILS", ""))
+	if len(emails) == 0 {
+		return nil
+	}
+
+	cursor, err := config.UsersCollection.Find(ctx,
+		bson.M{"email": bson.M{"$in": emails}, "email_verified": true, "role": bson.M{"$ne": models.RoleAdmin}},
+		options.Find().SetProjection(bson.M{"_id": 1, "email": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		result, err := config.UsersCollection.UpdateOne(ctx,
+			bson.M{"_id": user.ID, "role": bson.M{"$ne": models.RoleAdmin}},
+			bson.M{"$set": bson.M{"role": models.RoleAdmin, "updated_at": time.Now()}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount == 0 {
+			continue
+		}
+
+		log.Printf("Promoted %s to admin from ADMIN_EMAILS", user.Email)
+		userID := user.ID
+		if err := RecordAuditEvent(ctx, &models.AuditEvent{
+			Event:        models.AuditRoleChange,
+			Outcome:      models.AuditSuccess,
+			TargetUserID: &userID,
+			Metadata:     map[string]string{"role": string(models.RoleAdmin), "source": "ADMIN_EMAILS"},
+		}); err != nil {
+			log.Println("Failed to record admin bootstrap:", err)
+		}
+	}
+	return nil
+}
+
+// parseAdminEmails splits and normalises the ADMIN_EMAILS list
+func parseAdminEmails(value string) []string {
+	var emails []string
+	for _, email := range strings.Split(value, ",") {
+		if email = utils.NormalizeEmail(email); email != "" {
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"backend/models"
+)
+
+func TestParseAdminEmails(t *testing.T) {
+	got := parseAdminEmails(" Root@Example.com, ,ops@example.com,")
+	want := []string{"root@example.com", "ops@example.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseAdminEmails() = %v, want %v", got, want)
+	}
+}
+
+func TestBootstrapAdmins(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("promotes verified listed account", func(mt *mtest.T) {
+		useMockCollections(mt)
+		mt.Setenv("ADMIN_EMAILS", "Root@Example.com")
+
+		userID := primitive.NewObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "taskapp.users", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: userID}, {Key: "email", Value: "root@example.com"}}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			mtest.CreateSuccessResponse(),
+		)
+
+		if err := BootstrapAdmins(context.Background()); err != nil {
+			mt.Fatalf("BootstrapAdmins: %v", err)
+		}
+
+		filter := startedCommand(mt, "find").Lookup("filter").Document()
+		if email := filter.Lookup("email", "$in", "0").StringValue(); email != "root@example.com" {
+			mt.Fatalf("looked up %q, want the normalised address", email)
+		}
+		if !filter.Lookup("email_verified").Boolean() {
+			mt.Fatal("unverified accounts must not be promoted")
+		}
+
+		update := startedCommand(mt, "update").Lookup("updates", "0")
+		if update.Document().Lookup("q", "_id").ObjectID() != userID {
+			mt.Fatal("promoted the wrong account")
+		}
+		if role := update.Document().Lookup("u", "$set", "role").StringValue(); role != string(models.RoleAdmin) {
+			mt.Fatalf("role set to %q", role)
+		}
+
+		audit := startedCommand(mt, "insert").Lookup("documents", "0").Document()
+		if audit.Lookup("event").StringValue() != models.AuditRoleChange {
+			mt.Fatalf("audit event %v", audit)
+		}
+	})
+
+	mt.Run("already admin", func(mt *mtest.T) {
+		useMockCollections(mt)
+		mt.Setenv("ADMIN_EMAILS", "root@example.com")
+
+		// Nothing left to promote, so nothing is written
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "taskapp.users", mtest.FirstBatch))
+
+		if err := BootstrapAdmins(context.Background()); err != nil {
+			mt.Fatalf("BootstrapAdmins: %v", err)
+		}
+		if events := mt.GetAllStartedEvents(); len(events) != 1 {
+			mt.Fatalf("sent %d commands, want only the lookup", len(events))
+		}
+	})
+
+	mt.Run("unset", func(mt *mtest.T) {
+		useMockCollections(mt)
+		mt.Setenv("ADMIN_EMAILS", "")
+
+		if err := BootstrapAdmins(context.Background()); err != nil {
+			mt.Fatalf("BootstrapAdmins: %v", err)
+		}
+		if events := mt.GetAllStartedEvents(); len(events) != 0 {
+			mt.Fatalf("sent %d commands with ADMIN_EMAILS unset", len(events))
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+)
+
+const (
+	// VerificationTokenTTL is how long an emailed verification link stays valid
+	VerificationTokenTTL = 24 * time.Hour
+	// VerificationResendInterval throttles how often a link can be re-sent
+	VerificationResendInterval = time.Minute
+)
+
+// SendVerificationEmail - Emails a signed link that confirms the user's address
+func SendVerificationEmail(user *models.User) error {
+	token, err := utils.GenerateActionToken(utils.PurposeEmailVerification, user.ID.Hex(), user.Email, VerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/auth/verify?token=%s", config.AppBaseURL(), url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s.\n",
+		user.Name, link, VerificationTokenTTL)
+
+	return SendMail(user.Email, "Verify your email address", body)
+}
+
+// ResendVerificationEmail - Re-sends the verification link unless one was sent
+// within VerificationResendInterval. The throttle is claimed atomically so
+// concurrent requests send at most one email.
+func ResendVerificationEmail(ctx context.Context, email string) error {
+	now := time.Now()
+	var user models.User
+	err := config.UsersCollection.FindOneAndUpdate(ctx,
+		bson.M{
+			"email":          email,
+			"email_verified": bson.M{"$ne": true},
+			"$or": []bson.M{
+				{"verification_sent_at": bson.M{"$exists": false}},
+				{"verification_sent_at": bson.M{"$lt": now.Add(-VerificationResendInterval)}},
+			},
+		},
+		bson.M{"$set": bson.M{"verification_sent_at": now}},
+	).Decode(&user)
+	if err != nil {
+		return err
+	}
+
+	return SendVerificationEmail(&user)
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend/config"
+	"backend/models"
+)
+
+var (
+	ErrNotWorkspaceMember = errors.New("not a member of this workspace")
+	ErrLastWorkspaceAdmin = errors.New("a workspace must keep at least one admin")
+)
+
+// CreateWorkspace - Creates a workspace and makes owner its first admin
+func CreateWorkspace(ctx context.Context, name string, ownerID primitive.ObjectID) (*models.Workspace, error) {
+	now := time.Now()
+	workspace := &models.Workspace{
+		ID:        primitive.NewObjectID(),
+		Name:      name,
+		CreatedBy: ownerID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := config.WorkspacesCollection.InsertOne(ctx, workspace); err != nil {
+		return nil, err
+	}
+
+	if _, err := AddWorkspaceMember(ctx, workspace.ID, ownerID, models.RoleAdmin); err != nil {
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+// EnsurePersonalWorkspace - Makes sure the user administers a workspace of
+// their own and returns its ID. A workspace left without members by an
+// interrupted earlier attempt is reused, so calling this again is safe.
+func EnsurePersonalWorkspace(ctx context.Context, user *models.User) (primitive.ObjectID, error) {
+	cursor, err := config.WorkspacesCollection.Find(ctx, bson.M{"created_by": user.ID}, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	var owned []models.Workspace
+	if err := cursor.All(ctx, &owned); err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	for _, workspace := range owned {
+		membership, err := GetWorkspaceMembership(ctx, workspace.ID, user.ID)
+		if err == nil && membership.Role == models.RoleAdmin {
+			return workspace.ID, nil
+		} else if err != nil && err != ErrNotWorkspaceMember {
+			return primitive.NilObjectID, err
+		}
+
+		members, err := config.WorkspaceMembersCollection.CountDocuments(ctx, bson.M{"workspace_id": workspace.ID})
+		if err != nil {
+			return primitive.NilObjectID, err
+		}
+		if members == 0 {
+			if _, err := AddWorkspaceMember(ctx, workspace.ID, user.ID, models.RoleAdmin); err != nil && !mongo.IsDuplicateKeyError(err) {
+				return primitive.NilObjectID, err
+			}
+			return workspace.ID, nil
+		}
+	}
+
+	workspace, err := CreateWorkspace(ctx, user.Name+"'s workspace", user.ID)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return workspace.ID, nil
+}
+
+// MigrateToWorkspaces - Brings data from before workspaces existed into them:
+// every user without a membership gets a personal workspace, and tasks
+// without a workspace move into their creator's, or their first assignee's
+// when no creator was recorded. Safe to run on every start.
+func MigrateToWorkspaces(ctx context.Context) error {
+	memberIDs, err := config.WorkspaceMembersCollection.Distinct(ctx, "user_id", bson.M{})
+	if err != nil {
+		return err
+	}
+
+	cursor, err := config.UsersCollection.Find(ctx,
+		bson.M{"_id": bson.M{"$nin": memberIDs}},
+		options.Find().SetProjection(bson.M{"_id": 1, "name": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return err
+	}
+	for i := range users {
+		if _, err := EnsurePersonalWorkspace(ctx, &users[i]); err != nil {
+			return err
+		}
+	}
+	if len(users) > 0 {
+		log.Printf("Created personal workspaces for %d users", len(users))
+	}
+
+	orphaned := bson.M{"workspace_id": bson.M{"$in": bson.A{nil, primitive.NilObjectID}}}
+	cursor, err = config.TasksCollection.Find(ctx, orphaned,
+		options.Find().SetProjection(bson.M{"_id": 1, "created_by": 1, "assigned_to": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	var tasks []models.Task
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return err
+	}
+
+	taskIDsByOwner := map[primitive.ObjectID][]primitive.ObjectID{}
+	unowned := 0
+	for _, task := range tasks {
+		ownerID, ok := legacyTaskOwner(&task)
+		if !ok {
+			unowned++
+			continue
+		}
+		taskIDsByOwner[ownerID] = append(taskIDsByOwner[ownerID], task.ID)
+	}
+
+	for ownerID, taskIDs := range taskIDsByOwner {
+		var owner models.User
+		if err := config.UsersCollection.FindOne(ctx, bson.M{"_id": ownerID}).Decode(&owner); err == mongo.ErrNoDocuments {
+			unowned += len(taskIDs)
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		workspaceID, err := EnsurePersonalWorkspace(ctx, &owner)
+		if err != nil {
+			return err
+		}
+
+		filter := bson.M{"_id": bson.M{"$in": taskIDs}}
+		for key, value := range orphaned {
+			filter[key] = value
+		}
+		result, err := config.TasksCollection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"workspace_id": workspaceID}})
+		if err != nil {
+			return err
+		}
+		log.Printf("Moved %d tasks into the workspace of user %s", result.ModifiedCount, ownerID.Hex())
+	}
+	if unowned > 0 {
+		log.Printf("%d tasks have no creator or assignee with an account and remain outside any workspace", unowned)
+	}
+
+	return nil
+}
+
+// legacyTaskOwner picks whose personal workspace an orphaned task moves into.
+// Tasks from before creators were recorded fall back to their first assignee.
+func legacyTaskOwner(task *models.Task) (primitive.ObjectID, bool) {
+	if !task.CreatedBy.IsZero() {
+		return task.CreatedBy, true
+	}
+	for _, assignee := range task.AssignedTo {
+		if !assignee.IsZero() {
+			return assignee, true
+		}
+	}
+	return primitive.NilObjectID, false
+}
+
+// AddWorkspaceMember - Adds a user to a workspace with the given role
+func AddWorkspaceMember(ctx context.Context, workspaceID, userID primitive.ObjectID, role models.Role) (*models.WorkspaceMember, error) {
+	now := time.Now()
+	member := &models.WorkspaceMember{
+		ID:          primitive.NewObjectID(),
+		WorkspaceID: workspaceID,
+		UserID:      userID,
+		Role:        role,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if _, err := config.WorkspaceMembersCollection.InsertOne(ctx, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// GetWorkspaceMembership - Returns the user's membership, or ErrNotWorkspaceMember
+func GetWorkspaceMembership(ctx context.Context, workspaceID, userID primitive.ObjectID) (*models.WorkspaceMember, error) {
+	var member models.WorkspaceMember
+	err := config.WorkspaceMembersCollection.FindOne(ctx, bson.M{"workspace_id": workspaceID, "user_id": userID}).Decode(&member)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotWorkspaceMember
+	} else if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// WorkspaceNonMembers - Returns the IDs in userIDs that aren't members of the workspace
+func WorkspaceNonMembers(ctx context.Context, workspaceID primitive.ObjectID, userIDs []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	found, err := config.WorkspaceMembersCollection.Distinct(ctx, "user_id", bson.M{
+		"workspace_id": workspaceID,
+		"user_id":      bson.M{"$in": userIDs},
+	})
+	if err != nil {
+		return nil, err
+	}
+	members := make(map[primitive.ObjectID]bool, len(found))
+	for _, raw := range found {
+		if id, ok := raw.(primitive.ObjectID); ok {
+			members[id] = true
+		}
+	}
+
+	var missing []primitive.ObjectID
+	for _, id := range userIDs {
+		if !members[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+// ensureAnotherAdmin returns ErrLastWorkspaceAdmin if userID is the only admin left
+func ensureAnotherAdmin(ctx context.Context, workspaceID, userID primitive.ObjectID) error {
+	count, err := config.WorkspaceMembersCollection.CountDocuments(ctx, bson.M{
+		"workspace_id": workspaceID,
+		"role":         models.RoleAdmin,
+		"user_id":      bson.M{"$ne": userID},
+	})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrLastWorkspaceAdmin
+	}
+	return nil
+}
+
+// UpdateWorkspaceMemberRole - Changes a member's role, keeping at least one admin
+func UpdateWorkspaceMemberRole(ctx context.Context, workspaceID, userID primitive.ObjectID, role models.Role) error {
+	member, err := GetWorkspaceMembership(ctx, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if member.Role == models.RoleAdmin && role != models.RoleAdmin {
+		if err := ensureAnotherAdmin(ctx, workspaceID, userID); err != nil {
+			return err
+		}
+	}
+
+	_, err = config.WorkspaceMembersCollection.UpdateOne(ctx,
+		bson.M{"_id": member.ID},
+		bson.M{"$set": bson.M{"role": role, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// RemoveWorkspaceMember - Removes a member, keeping at least one admin
+func RemoveWorkspaceMember(ctx context.Context, workspaceID, userID primitive.ObjectID) error {
+	member, err := GetWorkspaceMembership(ctx, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if member.Role == models.RoleAdmin {
+		if err := ensureAnotherAdmin(ctx, workspaceID, userID); err != nil {
+			return err
+		}
+	}
+
+	_, err = config.WorkspaceMembersCollection.DeleteOne(ctx, bson.M{"_id": member.ID})
+	return err
+}
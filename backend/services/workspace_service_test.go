@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"backend/config"
+	"backend/models"
+)
+
+// useMockCollections points the collections at a mock deployment that
+// answers with the responses queued through mt.AddMockResponses
+func useMockCollections(mt *mtest.T) {
+	db := mt.Client.Database("taskapp")
+	config.UsersCollection = db.Collection("users")
+	config.TasksCollection = db.Collection("tasks")
+	config.WorkspacesCollection = db.Collection("workspaces")
+	config.WorkspaceMembersCollection = db.Collection("workspace_members")
+	config.WorkspaceInvitationsCollection = db.Collection("workspace_invitations")
+	config.SessionsCollection = db.Collection("sessions")
+	config.RefreshTokensCollection = db.Collection("refresh_tokens")
+	config.RevokedTokensCollection = db.Collection("revoked_tokens")
+	config.AuditEventsCollection = db.Collection("audit_events")
+}
+
+// startedCommand returns the first command of the given name sent to the mock
+func startedCommand(mt *mtest.T, name string) bson.Raw {
+	for _, event := range mt.GetAllStartedEvents() {
+		if event.CommandName == name {
+			return event.Command
+		}
+	}
+	mt.Fatalf("no %s command was sent", name)
+	return nil
+}
+
+// baselineTask is a task document as written before creators and workspaces
+// were recorded
+func baselineTask(id, assignee primitive.ObjectID) bson.D {
+	now := time.Now()
+	task := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "title", Value: "Legacy task"},
+		{Key: "status", Value: "pending"},
+		{Key: "priority", Value: "medium"},
+		{Key: "created_at", Value: now},
+		{Key: "updated_at", Value: now},
+	}
+	if !assignee.IsZero() {
+		task = append(task, bson.E{Key: "assigned_to", Value: bson.A{assignee}})
+	}
+	return task
+}
+
+func TestLegacyTaskOwner(t *testing.T) {
+	creator, assignee := primitive.NewObjectID(), primitive.NewObjectID()
+
+	tests := []struct {
+		name   string
+		task   models.Task
+		want   primitive.ObjectID
+		wantOK bool
+	}{
+		{name: "creator wins", task: models.Task{CreatedBy: creator, AssignedTo: []primitive.ObjectID{assignee}}, want: creator, wantOK: true},
+		{name: "first assignee without creator", task: models.Task{AssignedTo: []primitive.ObjectID{assignee, creator}}, want: assignee, wantOK: true},
+		{name: "nobody", task: models.Task{}, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := legacyTaskOwner(&tt.task)
+			if ok != tt.wantOK || got != tt.want {
+				t.Fatalf("legacyTaskOwner() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMigrateToWorkspacesMovesBaselineTasksToAssignee(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("baseline task", func(mt *mtest.T) {
+		useMockCollections(mt)
+
+		assigneeID, taskID, workspaceID := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+		now := time.Now()
+
+		mt.AddMockResponses(
+			// Every user already has a workspace
+			bson.D{{Key: "ok", Value: 1}, {Key: "values", Value: bson.A{assigneeID}}},
+			mtest.CreateCursorResponse(0, "taskapp.users", mtest.FirstBatch),
+			// One baseline task with an assignee and one with nobody to own it
+			mtest.CreateCursorResponse(0, "taskapp.tasks", mtest.FirstBatch,
+				baselineTask(taskID, assigneeID),
+				baselineTask(primitive.NewObjectID(), primitive.NilObjectID),
+			),
+			mtest.CreateCursorResponse(0, "taskapp.users", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: assigneeID}, {Key: "name", Value: "Assignee"}}),
+			mtest.CreateCursorResponse(0, "taskapp.workspaces", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: workspaceID}, {Key: "created_by", Value: assigneeID}, {Key: "created_at", Value: now}}),
+			mtest.CreateCursorResponse(0, "taskapp.workspace_members", mtest.FirstBatch,
+				bson.D{{Key: "workspace_id", Value: workspaceID}, {Key: "user_id", Value: assigneeID}, {Key: "role", Value: string(models.RoleAdmin)}}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		)
+
+		if err := MigrateToWorkspaces(context.Background()); err != nil {
+			mt.Fatalf("MigrateToWorkspaces: %v", err)
+		}
+
+		update := startedCommand(mt, "update").Lookup("updates", "0")
+		var statement struct {
+			Q struct {
+				ID struct {
+					In []primitive.ObjectID `bson:"$in"`
+				} `bson:"_id"`
+			} `bson:"q"`
+			U struct {
+				Set struct {
+					WorkspaceID primitive.ObjectID `bson:"workspace_id"`
+				} `bson:"$set"`
+			} `bson:"u"`
+		}
+		if err := update.Unmarshal(&statement); err != nil {
+			mt.Fatal(err)
+		}
+		if len(statement.Q.ID.In) != 1 || statement.Q.ID.In[0] != taskID {
+			mt.Fatalf("updated tasks %v, want only %v", statement.Q.ID.In, taskID)
+		}
+		if statement.U.Set.WorkspaceID != workspaceID {
+			mt.Fatalf("moved into %v, want %v", statement.U.Set.WorkspaceID, workspaceID)
+		}
+	})
+}
+
+func TestEnsurePersonalWorkspaceReusesEmptyWorkspace(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("empty workspace", func(mt *mtest.T) {
+		useMockCollections(mt)
+
+		user := &models.User{ID: primitive.NewObjectID(), Name: "Owner"}
+		workspaceID := primitive.NewObjectID()
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "taskapp.workspaces", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: workspaceID}, {Key: "created_by", Value: user.ID}}),
+			// Not a member, and nobody else is either
+			mtest.CreateCursorResponse(0, "taskapp.workspace_members", mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, "taskapp.workspace_members", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+		)
+
+		got, err := EnsurePersonalWorkspace(context.Background(), user)
+		if err != nil {
+			mt.Fatalf("EnsurePersonalWorkspace: %v", err)
+		}
+		if got != workspaceID {
+			mt.Fatalf("got workspace %v, want the existing %v", got, workspaceID)
+		}
+		for _, event := range mt.GetAllStartedEvents() {
+			if event.CommandName == "insert" && event.Command.Lookup("insert").StringValue() == "workspaces" {
+				mt.Fatal("created a second workspace")
+			}
+		}
+	})
+}
+
+func TestWorkspaceNonMembers(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("one outsider", func(mt *mtest.T) {
+		useMockCollections(mt)
+
+		member, outsider := primitive.NewObjectID(), primitive.NewObjectID()
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "values", Value: bson.A{member}}})
+
+		missing, err := WorkspaceNonMembers(context.Background(), primitive.NewObjectID(), []primitive.ObjectID{member, outsider})
+		if err != nil {
+			mt.Fatalf("WorkspaceNonMembers: %v", err)
+		}
+		if len(missing) != 1 || missing[0] != outsider {
+			mt.Fatalf("got %v, want only %v", missing, outsider)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Purposes for single-action tokens sent in links
+const (
+	PurposeEmailVerification  = "email_verification"
+	PurposeTwoFactorChallenge = "two_factor_challenge"
+	PurposeOIDCLogin          = "oidc_login"
+	PurposeWorkspaceInvite    = "workspace_invite"
+	PurposeEmailChange        = "email_change"
+	PurposeAccountRestore     = "account_restore"
+	PurposeMagicLink          = "magic_link"
+)
+
+// ActionClaims are carried by short-lived tokens that authorize one action,
+// such as verifying an email address. They never carry user_id, so VerifyJWT
+// rejects them as access tokens.
+type ActionClaims struct {
+	Purpose string            `json:"purpose"`
+	Email   string            `json:"email,omitempty"`
+	Data    map[string]string `json:"data,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// GenerateActionToken signs a token for subject that is only valid for purpose
+func GenerateActionToken(purpose, subject, email string, ttl time.Duration) (string, error) {
+	return generateActionToken(ActionClaims{Purpose: purpose, Email: email}, subject, ttl)
+}
+
+// GenerateActionTokenWithData is GenerateActionToken for flows that need to
+// carry extra signed state, such as the OIDC nonce and PKCE verifier
+func GenerateActionTokenWithData(purpose, subject string, data map[string]string, ttl time.Duration) (string, error) {
+	return generateActionToken(ActionClaims{Purpose: purpose, Data: data}, subject, ttl)
+}
+
+func generateActionToken(claims ActionClaims, subject string, ttl time.Duration) (string, error) {
+	jti, err := GenerateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        jti,
+		Subject:   subject,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+	return signToken(claims)
+}
+
+// VerifyActionToken checks the signature, expiry and purpose of an action token
+func VerifyActionToken(tokenString, purpose string) (*ActionClaims, error) {
+	claims := new(ActionClaims)
+	token, err := parseToken(tokenString, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid || claims.Purpose != purpose || claims.Subject == "" {
+		return nil, errors.New("invalid action token")
+	}
+
+	return claims, nil
+}
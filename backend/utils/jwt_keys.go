@@ -0,0 +1,235 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// SigningKey is one entry of the keyring. Keys without a private part can
+// still verify tokens, which is how retired keys are kept during rotation.
+type SigningKey struct {
+	ID        string
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// minHMACSecretLength is the shortest HS256 secret accepted, whether it comes
+// from a key file or JWT_SECRET, so a guessable secret can't sign tokens
+const minHMACSecretLength = 32
+
+var (
+	signingMethod jwt.SigningMethod
+	activeKey     *SigningKey
+	signingKeys   = map[string]*SigningKey{}
+)
+
+// LoadSigningKeys reads the JWT keyring from the environment:
+//
+//	JWT_ALGORITHM       HS256 (default), RS256 or EdDSA; every key must use it
+//	JWT_KEYS_DIR        directory of <kid>.pem (RS256/EdDSA) or <kid>.key (HS256) files
+//	JWT_ACTIVE_KEY_ID   kid used to sign new tokens; optional with a single key
+//	JWT_SECRET          HS256 secret of at least 32 bytes, used when JWT_KEYS_DIR is not set
+func LoadSigningKeys() error {
+	alg := os.Getenv("JWT_ALGORITHM")
+	if alg == "" {
+		alg = jwt.SigningMethodHS256.Alg()
+	}
+
+	switch alg {
+	case jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg():
+		signingMethod = jwt.GetSigningMethod(alg)
+	default:
+		return fmt.Errorf("unsupported JWT_ALGORITHM %q", alg)
+	}
+
+	keys := map[string]*SigningKey{}
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		if alg != jwt.SigningMethodHS256.Alg() {
+			return errors.New("JWT_KEYS_DIR is required for asymmetric algorithms")
+		}
+		secret := os.Getenv("JWT_SECRET")
+		if secret == "" {
+			return errors.New("JWT_SECRET not set")
+		}
+		if len(secret) < minHMACSecretLength {
+			return fmt.Errorf("JWT_SECRET must be at least %d bytes", minHMACSecretLength)
+		}
+		keys["default"] = &SigningKey{ID: "default", signKey: []byte(secret), verifyKey: []byte(secret)}
+	} else {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			kid := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+			data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return err
+			}
+			key, err := parseSigningKey(alg, kid, data)
+			if err != nil {
+				return fmt.Errorf("key %s: %w", entry.Name(), err)
+			}
+			keys[kid] = key
+		}
+	}
+
+	if len(keys) == 0 {
+		return errors.New("no JWT signing keys found")
+	}
+
+	activeID := os.Getenv("JWT_ACTIVE_KEY_ID")
+	if activeID == "" {
+		if len(keys) > 1 {
+			return errors.New("JWT_ACTIVE_KEY_ID is required when several keys are configured")
+		}
+		for kid := range keys {
+			activeID = kid
+		}
+	}
+
+	active, ok := keys[activeID]
+	if !ok {
+		return fmt.Errorf("active key %q not found", activeID)
+	}
+	if active.signKey == nil {
+		return fmt.Errorf("active key %q has no private key", activeID)
+	}
+
+	signingKeys = keys
+	activeKey = active
+	return nil
+}
+
+// parseSigningKey decodes a key file for the configured algorithm
+func parseSigningKey(alg, kid string, data []byte) (*SigningKey, error) {
+	if alg == jwt.SigningMethodHS256.Alg() {
+		secret := []byte(strings.TrimSpace(string(data)))
+		if len(secret) < minHMACSecretLength {
+			return nil, fmt.Errorf("HS256 secrets must be at least %d bytes", minHMACSecretLength)
+		}
+		return &SigningKey{ID: kid, signKey: secret, verifyKey: secret}, nil
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.signKey, key.verifyKey = k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.verifyKey = k
+	case ed25519.PrivateKey:
+		key.signKey, key.verifyKey = k, k.Public()
+	case ed25519.PublicKey:
+		key.verifyKey = k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+
+	_, isRSA := key.verifyKey.(*rsa.PublicKey)
+	if (alg == jwt.SigningMethodRS256.Alg()) != isRSA {
+		return nil, fmt.Errorf("key type does not match %s", alg)
+	}
+
+	return key, nil
+}
+
+// signToken signs claims with the active key and stamps its kid in the header
+func signToken(claims jwt.Claims) (string, error) {
+	if activeKey == nil {
+		return "", errors.New("signing keys not loaded")
+	}
+	token := jwt.NewWithClaims(signingMethod, claims)
+	token.Header["kid"] = activeKey.ID
+	return token.SignedString(activeKey.signKey)
+}
+
+// parseToken verifies a token against the keyring, accepting only the
+// configured algorithm regardless of what the token header claims
+func parseToken(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	if signingMethod == nil {
+		return nil, errors.New("signing keys not loaded")
+	}
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := signingKeys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		return key.verifyKey, nil
+	}, jwt.WithValidMethods([]string{signingMethod.Alg()}))
+}
+
+// JWKS returns the public keys as a JSON Web Key Set. Symmetric keys are
+// never published, so the set is empty when running with HS256.
+func JWKS() map[string]interface{} {
+	kids := make([]string, 0, len(signingKeys))
+	for kid := range signingKeys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	keys := []map[string]string{}
+	for _, kid := range kids {
+		switch k := signingKeys[kid].verifyKey.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": jwt.SigningMethodRS256.Alg(),
+				"kid": kid,
+				"n":   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, map[string]string{
+				"kty": "OKP",
+				"use": "sig",
+				"alg": jwt.SigningMethodEdDSA.Alg(),
+				"crv": "Ed25519",
+				"kid": kid,
+				"x":   base64.RawURLEncoding.EncodeToString(k),
+			})
+		}
+	}
+
+	return map[string]interface{}{"keys": keys}
+}
@@ -1,52 +1,84 @@
 package utils
 
 import (
-	"log"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+)
 
-	"golang.org/x/crypto/bcrypt"
+const (
+	// AccessTokenTTL is kept short; clients renew through /auth/refresh
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL bounds how long a login can be kept alive by rotation
+	RefreshTokenTTL = 7 * 24 * time.Hour
 )
 
-var jwtSecret = []byte("your_secret_key")
+// Claims carried by access tokens. SessionID ties the token to the refresh
+// token family created at login so logout can revoke both.
+type Claims struct {
+	UserID    string      `json:"user_id"`
+	SessionID string      `json:"sid,omitempty"`
+	Actor     *ActorClaim `json:"act,omitempty"`
+	jwt.RegisteredClaims
+}
 
-// Generate JWT token
-func GenerateJWT(userID string) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+// ActorClaim names the admin acting on behalf of the token's user during
+// impersonation, as in the RFC 8693 "act" claim
+type ActorClaim struct {
+	Subject string `json:"sub"`
 }
 
-// Verify JWT token
-func VerifyJWT(tokenString string) (string, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+// Generate JWT token. The claims are returned so callers can record the jti.
+func GenerateJWT(userID, sessionID string) (string, *Claims, error) {
+	return generateAccessToken(Claims{UserID: userID, SessionID: sessionID})
+}
 
+// GenerateImpersonationJWT issues an access token for userID that records
+// actorID as the admin acting on their behalf. It belongs to no session, so
+// it can't be refreshed and simply expires.
+func GenerateImpersonationJWT(userID, actorID string) (string, *Claims, error) {
+	return generateAccessToken(Claims{UserID: userID, Actor: &ActorClaim{Subject: actorID}})
+}
+
+func generateAccessToken(claims Claims) (string, *Claims, error) {
+	jti, err := GenerateRandomToken(16)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return claims["user_id"].(string), nil
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        jti,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
 	}
-
-	return "", err
+	token, err := signToken(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, &claims, nil
 }
 
-// HashPassword generates a bcrypt hash of the password
-func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(hashedPassword), err
+// IsExpiredTokenError reports whether a VerifyJWT error means only that the
+// token expired, as opposed to a bad signature or malformed token
+func IsExpiredTokenError(err error) bool {
+	var validationErr *jwt.ValidationError
+	return errors.As(err, &validationErr) && validationErr.Errors == jwt.ValidationErrorExpired
 }
 
-// CheckPasswordHash verifies a password against a hashed password
-func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	log.Println(err)
-	return err == nil
+// Verify JWT token
+func VerifyJWT(tokenString string) (*Claims, error) {
+	claims := new(Claims)
+	token, err := parseToken(tokenString, claims)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid || claims.UserID == "" || claims.ID == "" {
+		return nil, errors.New("invalid token claims")
+	}
+
+	return claims, nil
 }
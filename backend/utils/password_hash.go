@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashes are stored in the PHC string format, which records the
+// algorithm, version and cost parameters next to the salt and key:
+//
+//	$argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>
+//
+// Hashes created before Argon2id was introduced are plain bcrypt ("$2a$...")
+// and are still accepted; NeedsRehash reports them so they can be upgraded.
+const argon2idPrefix = "$argon2id$"
+
+// Argon2Params are the Argon2id cost parameters for new hashes
+type Argon2Params struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Defaults follow the second recommended option in RFC 9106
+var argon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 4,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var errInvalidHash = errors.New("invalid password hash")
+
+// argon2Slots bounds how many Argon2id computations run at once. Each one
+// allocates Memory KiB, and login is unauthenticated, so without a bound a
+// burst of requests could exhaust the server's memory.
+var argon2Slots = make(chan struct{}, runtime.NumCPU())
+
+// dummyHash is checked against when there is no real hash, so that unknown
+// emails and password-less accounts take as long as a wrong password
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// LoadPasswordHasher reads Argon2id parameters from the environment:
+//
+//	ARGON2_MEMORY_KIB    memory in KiB, at least 19456 (default 65536)
+//	ARGON2_ITERATIONS    passes over memory, at least 1 (default 3)
+//	ARGON2_PARALLELISM   lanes, 1-255 (default 4)
+//	ARGON2_MAX_CONCURRENT  hashes computed at once (default: number of CPUs)
+//
+// Changing them only affects new hashes; existing ones are upgraded at login.
+func LoadPasswordHasher() error {
+	if value := os.Getenv("ARGON2_MEMORY_KIB"); value != "" {
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || n < 19*1024 {
+			return fmt.Errorf("ARGON2_MEMORY_KIB must be a number of KiB of at least %d", 19*1024)
+		}
+		argon2Params.Memory = uint32(n)
+	}
+
+	if value := os.Getenv("ARGON2_ITERATIONS"); value != "" {
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || n < 1 {
+			return fmt.Errorf("ARGON2_ITERATIONS must be at least 1")
+		}
+		argon2Params.Iterations = uint32(n)
+	}
+
+	if value := os.Getenv("ARGON2_PARALLELISM"); value != "" {
+		n, err := strconv.ParseUint(value, 10, 8)
+		if err != nil || n < 1 {
+			return fmt.Errorf("ARGON2_PARALLELISM must be between 1 and 255")
+		}
+		argon2Params.Parallelism = uint8(n)
+	}
+
+	if value := os.Getenv("ARGON2_MAX_CONCURRENT"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("ARGON2_MAX_CONCURRENT must be at least 1")
+		}
+		argon2Slots = make(chan struct{}, n)
+	}
+
+	return nil
+}
+
+// argon2IDKey derives an Argon2id key once a slot is free
+func argon2IDKey(password, salt []byte, p Argon2Params, keyLength uint32) []byte {
+	argon2Slots <- struct{}{}
+	defer func() { <-argon2Slots }()
+
+	return argon2.IDKey(password, salt, p.Iterations, p.Memory, p.Parallelism, keyLength)
+}
+
+// HashPassword generates an Argon2id hash of the password in PHC format
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argon2Params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	p := argon2Params
+	key := argon2IDKey([]byte(password), salt, p, p.KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// CheckPasswordHash verifies a password against an Argon2id or legacy bcrypt
+// hash. An empty hash (an unknown user, or an account without a password)
+// never matches but costs the same as a real check, so timing doesn't reveal
+// which accounts exist.
+func CheckPasswordHash(password, hash string) bool {
+	if hash == "" {
+		dummyHashOnce.Do(func() {
+			dummyHash, _ = HashPassword("dummy password for timing")
+		})
+		if dummyHash != "" {
+			CheckPasswordHash(password, dummyHash)
+		}
+		return false
+	}
+	if !strings.HasPrefix(hash, argon2idPrefix) {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+
+	p, salt, key, err := decodeArgon2idHash(hash)
+	if err != nil {
+		return false
+	}
+
+	candidate := argon2IDKey([]byte(password), salt, p, uint32(len(key)))
+	return subtle.ConstantTimeCompare(candidate, key) == 1
+}
+
+// NeedsRehash reports whether hash uses bcrypt or Argon2id parameters other
+// than the current ones, so it should be replaced after a successful login
+func NeedsRehash(hash string) bool {
+	if !strings.HasPrefix(hash, argon2idPrefix) {
+		return true
+	}
+
+	p, salt, key, err := decodeArgon2idHash(hash)
+	if err != nil {
+		return true
+	}
+
+	return p.Memory != argon2Params.Memory ||
+		p.Iterations != argon2Params.Iterations ||
+		p.Parallelism != argon2Params.Parallelism ||
+		uint32(len(salt)) != argon2Params.SaltLength ||
+		uint32(len(key)) != argon2Params.KeyLength
+}
+
+func decodeArgon2idHash(hash string) (Argon2Params, []byte, []byte, error) {
+	var p Argon2Params
+
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, errInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errInvalidHash
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, errInvalidHash
+	}
+	if p.Memory == 0 || p.Iterations == 0 || p.Parallelism == 0 {
+		return p, nil, nil, errInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, errInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, errInvalidHash
+	}
+
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	return p, salt, key, nil
+}
@@ -0,0 +1,194 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxPasswordLength bounds the work a single login or registration can cause
+const maxPasswordLength = 128
+
+// PasswordPolicy holds the rules new passwords must satisfy
+type PasswordPolicy struct {
+	MinLength  int // Minimum length in characters
+	MinClasses int // How many of lowercase, uppercase, digits and symbols must appear
+}
+
+var passwordPolicy = PasswordPolicy{MinLength: 10, MinClasses: 3}
+
+// breachedPasswords is a SHA-1 hash list bucketed by the first five hex
+// characters, the same k-anonymity layout as the Have I Been Pwned range API.
+// Lookups only ever compare suffixes within one bucket.
+var breachedPasswords = map[string][]string{}
+
+//go:embed breached_passwords.txt
+var embeddedBreachedPasswords string
+
+func init() {
+	if err := addBreachedHashes(strings.NewReader(embeddedBreachedPasswords)); err != nil {
+		panic("invalid embedded breached password list: " + err.Error())
+	}
+	sortBreachedBuckets()
+}
+
+// LoadPasswordPolicy reads the policy from the environment:
+//
+//	PASSWORD_MIN_LENGTH       minimum length, at least 8 (default 10)
+//	PASSWORD_MIN_CLASSES      character classes required, 1-4 (default 3)
+//	BREACHED_PASSWORDS_FILE   optional extra list of SHA-1 hashes, one per line,
+//	                          optionally followed by ":count" as in HIBP dumps
+func LoadPasswordPolicy() error {
+	if value := os.Getenv("PASSWORD_MIN_LENGTH"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 8 || n > maxPasswordLength {
+			return fmt.Errorf("PASSWORD_MIN_LENGTH must be between 8 and %d", maxPasswordLength)
+		}
+		passwordPolicy.MinLength = n
+	}
+
+	if value := os.Getenv("PASSWORD_MIN_CLASSES"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 4 {
+			return fmt.Errorf("PASSWORD_MIN_CLASSES must be between 1 and 4")
+		}
+		passwordPolicy.MinClasses = n
+	}
+
+	if path := os.Getenv("BREACHED_PASSWORDS_FILE"); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open breached password list: %w", err)
+		}
+		defer f.Close()
+
+		if err := addBreachedHashes(f); err != nil {
+			return fmt.Errorf("invalid breached password list %s: %w", path, err)
+		}
+		sortBreachedBuckets()
+	}
+
+	return nil
+}
+
+// ValidatePassword checks password against the policy and the breached list.
+// personalInfo (such as the user's email and name) must not appear in it.
+// Each violation is reported against field.
+func ValidatePassword(field, password string, personalInfo ...string) []FieldError {
+	var violations []FieldError
+	fail := func(rule, message string) {
+		violations = append(violations, FieldError{Field: field, Rule: rule, Message: message})
+	}
+
+	length := len([]rune(password))
+	if length < passwordPolicy.MinLength {
+		fail("password_length", fmt.Sprintf("must be at least %d characters", passwordPolicy.MinLength))
+	}
+	if len(password) > maxPasswordLength {
+		fail("password_length", fmt.Sprintf("must be at most %d bytes", maxPasswordLength))
+	}
+
+	if classes := characterClasses(password); classes < passwordPolicy.MinClasses {
+		fail("password_classes", fmt.Sprintf("must contain at least %d of: lowercase letters, uppercase letters, digits, symbols", passwordPolicy.MinClasses))
+	}
+
+	lower := strings.ToLower(password)
+	for _, info := range personalTokens(personalInfo) {
+		if strings.Contains(lower, info) {
+			fail("password_personal", "must not contain your name or email address")
+			break
+		}
+	}
+
+	if IsBreachedPassword(password) {
+		fail("password_breached", "has appeared in a data breach and can't be used, please choose another")
+	}
+
+	return violations
+}
+
+// IsBreachedPassword reports whether the password's SHA-1 hash is on the breached list
+func IsBreachedPassword(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	bucket := breachedPasswords[hash[:5]]
+	i := sort.SearchStrings(bucket, hash[5:])
+	return i < len(bucket) && bucket[i] == hash[5:]
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	count := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
+
+// personalTokens lowercases the personal info and splits emails and names into
+// the parts worth checking; very short parts are ignored to avoid false hits
+func personalTokens(personalInfo []string) []string {
+	var tokens []string
+	for _, info := range personalInfo {
+		info = strings.ToLower(strings.TrimSpace(info))
+		if local, _, found := strings.Cut(info, "@"); found {
+			info = local
+		}
+		parts := strings.FieldsFunc(info, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, part := range append(parts, info) {
+			if len(part) >= 3 {
+				tokens = append(tokens, part)
+			}
+		}
+	}
+	return tokens
+}
+
+func addBreachedHashes(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != 40 {
+			return fmt.Errorf("not a SHA-1 hash: %q", line)
+		}
+		breachedPasswords[hash[:5]] = append(breachedPasswords[hash[:5]], hash[5:])
+	}
+	return scanner.Err()
+}
+
+func sortBreachedBuckets() {
+	for _, bucket := range breachedPasswords {
+		sort.Strings(bucket)
+	}
+}